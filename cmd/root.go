@@ -1,16 +1,26 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
+	"github.com/sters/cc-session-tailing/internal/config"
 	"github.com/sters/cc-session-tailing/internal/parser"
 	"github.com/sters/cc-session-tailing/internal/session"
+	"github.com/sters/cc-session-tailing/internal/state"
+	"github.com/sters/cc-session-tailing/internal/stream"
+	"github.com/sters/cc-session-tailing/internal/theme"
 	"github.com/sters/cc-session-tailing/internal/tui"
 	"github.com/sters/cc-session-tailing/internal/watcher"
 )
@@ -24,11 +34,18 @@ func (e *ProjectNotFoundError) Error() string {
 	return fmt.Sprintf("claude project directory does not exist: %s\nMake sure Claude Code has been used in this project", e.Path)
 }
 
+// defaultStreamWidth is the line width of stream mode when the terminal width is unknown.
+const defaultStreamWidth = 100
+
 // CLI holds the command line interface state.
 type CLI struct {
 	panels      int
 	projectPath string
 	mode        string
+	layout      string
+	themeName   string
+	plain       bool
+	stream      bool
 	rootCmd     *cobra.Command
 }
 
@@ -43,7 +60,11 @@ Shows session activity in multiple panels with LRU-based panel assignment.
 
 View modes:
   tree  - Session tree on left, log viewport on right (default)
-  panel - Multiple panels side by side`,
+  panel - Multiple panels side by side or in a grid (see --layout)
+
+Plain mode (--plain, or the NO_COLOR environment variable) draws without colors,
+uses ASCII glyphs and spells out selection and activity states.
+Stream mode (--stream) prints new messages as plain text instead of running the TUI.`,
 		RunE: cli.runTUI,
 	}
 
@@ -63,6 +84,10 @@ View modes:
 	cli.rootCmd.Flags().IntVarP(&cli.panels, "panels", "p", 4, "Number of panels to display (panel mode)")
 	cli.rootCmd.Flags().StringVarP(&cli.projectPath, "project", "d", ".", "Project directory to watch")
 	cli.rootCmd.Flags().StringVarP(&cli.mode, "mode", "m", "", "View mode: tree or panel (default: tree, or panel if -p is specified)")
+	cli.rootCmd.Flags().StringVarP(&cli.layout, "layout", "l", "columns", "Panel layout: columns, rows, grid or CxR such as 2x2 (panel mode)")
+	cli.rootCmd.Flags().StringVar(&cli.themeName, "theme", "", "Color theme: auto, dark, light, high-contrast or a theme from the config file (default: auto)")
+	cli.rootCmd.Flags().BoolVar(&cli.plain, "plain", false, "Plain mode: no colors, ASCII glyphs and text labels for states (also enabled by NO_COLOR)")
+	cli.rootCmd.Flags().BoolVar(&cli.stream, "stream", false, "Print new messages as a linear plain-text stream instead of running the TUI")
 
 	return cli
 }
@@ -109,8 +134,67 @@ func (cli *CLI) runTUI(cmd *cobra.Command, _ []string) error {
 	}
 	defer func() { _ = w.Stop() }()
 
+	// Load UI state saved by a previous run. A broken state file only resets the UI;
+	// it is moved aside so that the state saved on exit does not overwrite it.
+	store, err := state.NewStore(claudeProjectPath)
+	if err != nil {
+		return fmt.Errorf("failed to locate state directory: %w", err)
+	}
+	saveState := true
+	savedState, err := store.Load()
+	if err != nil {
+		savedState = state.New()
+		backup, backupErr := store.Backup()
+		if backupErr != nil {
+			// Keep the file as it is rather than replace it.
+			saveState = false
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v; starting with a fresh UI state that will not be saved\n", err)
+		} else {
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v; starting with a fresh UI state (the file was moved to %s)\n", err, backup)
+		}
+	}
+
+	// Saved layout is used unless -l is given explicitly.
+	// A saved layout that does not parse is dropped in favor of the default.
+	layout, err := tui.ParseLayout(cli.layout)
+	if err != nil {
+		return fmt.Errorf("failed to parse layout: %w", err)
+	}
+	if !cmd.Flags().Changed("layout") && savedState.Layout != "" {
+		saved, err := tui.ParseLayout(savedState.Layout)
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: ignoring saved layout: %v\n", err)
+			savedState.Layout = ""
+		} else {
+			layout = saved
+		}
+	}
+
+	// Panel count: -p, then the size of a fixed layout given by -l, then saved state.
+	panels := cli.panels
+	if !cmd.Flags().Changed("panels") {
+		if cmd.Flags().Changed("layout") && layout.Kind == tui.LayoutFixed {
+			panels = layout.Cols * layout.Rows
+		} else if savedState.PanelCount > 0 {
+			panels = savedState.PanelCount
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Apply the color theme and plain mode before any style is created.
+	if cli.plainMode() {
+		theme.SetPlain(true)
+		lipgloss.SetColorProfile(termenv.Ascii)
+	} else if err := cli.applyTheme(cfg); err != nil {
+		return err
+	}
+
 	// Create session manager.
-	manager := session.NewManager(cli.panels)
+	manager := session.NewManager(panels)
 
 	// Scan existing files.
 	existingEvents, err := w.ScanExisting()
@@ -132,18 +216,92 @@ func (cli *CLI) runTUI(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
+	if cli.stream {
+		return runStream(manager, w)
+	}
+
 	// Determine view mode.
 	viewMode := cli.determineViewMode(cmd)
 
 	// Create TUI model.
+	keys, err := tui.NewKeyMap(cfg.Keys.Preset, cfg.Keys.Bindings)
+	if err != nil {
+		return fmt.Errorf("failed to configure key bindings: %w", err)
+	}
+
 	model := tui.NewModelWithMode(manager, w, viewMode)
+	model.SetKeyMap(keys)
+	model.SetThemes(cfg.Themes)
+	model.RestoreState(savedState)
+	model.SetLayout(layout)
 
 	// Run bubbletea program.
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		return fmt.Errorf("failed to run TUI: %w", err)
 	}
 
+	// Losing the UI state only resets the UI on the next run, so it does not fail the command.
+	if !saveState {
+		return nil
+	}
+	if err := store.Save(model.SaveState()); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: failed to save UI state: %v\n", err)
+	}
+
+	return nil
+}
+
+// plainMode returns whether to run in plain mode.
+// NO_COLOR is honored when set to any non-empty value (https://no-color.org).
+func (cli *CLI) plainMode() bool {
+	return cli.plain || cli.stream || os.Getenv("NO_COLOR") != ""
+}
+
+// runStream prints new messages as plain text until interrupted.
+func runStream(manager *session.Manager, w *watcher.Watcher) error {
+	width := defaultStreamWidth
+	if cols, _, err := term.GetSize(os.Stdout.Fd()); err == nil && cols > 0 {
+		width = cols
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Streaming new messages from %s (Ctrl+C to stop)\n", w.ProjectPath())
+	if err := stream.New(manager, w, os.Stdout, width).Run(ctx); err != nil {
+		return fmt.Errorf("failed to stream messages: %w", err)
+	}
+
+	return nil
+}
+
+// loadConfig reads the user config file.
+func loadConfig() (*config.Config, error) {
+	path, err := config.Path()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate config file: %w", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// applyTheme selects the color theme: --theme, then the config file, then automatic detection.
+func (cli *CLI) applyTheme(cfg *config.Config) error {
+	name := cfg.Theme
+	if cli.themeName != "" {
+		name = cli.themeName
+	}
+	t, err := theme.Resolve(name, cfg.Themes)
+	if err != nil {
+		return fmt.Errorf("failed to select theme: %w", err)
+	}
+	theme.Set(t)
+
 	return nil
 }
 
@@ -0,0 +1,67 @@
+// Package config reads the user configuration file.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// appName is the directory name used under the user's config directory.
+const appName = "cc-session-tailing"
+
+// Config holds the user configuration.
+type Config struct {
+	// Theme is the name of the theme to use: "auto", a built-in theme or a user theme.
+	Theme string `json:"theme,omitempty"`
+	// Themes holds user-defined themes by name.
+	Themes map[string]theme.Spec `json:"themes,omitempty"`
+	// Keys configures the key bindings.
+	Keys Keys `json:"keys,omitempty"`
+}
+
+// Keys configures the key bindings.
+type Keys struct {
+	// Preset is the set of bindings to start from: "default", "emacs" or "arrows".
+	Preset string `json:"preset,omitempty"`
+	// Bindings replaces the keys of bindings by name; an empty list disables a binding.
+	Bindings map[string][]string `json:"bindings,omitempty"`
+}
+
+// Path returns the path of the config file.
+// $XDG_CONFIG_HOME is used when set, otherwise ~/.config.
+func Path() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dir = filepath.Join(homeDir, ".config")
+	}
+
+	return filepath.Join(dir, appName, "config.json"), nil
+}
+
+// Load reads the config file at path.
+// A missing file is not an error and yields an empty config.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
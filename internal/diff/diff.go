@@ -0,0 +1,262 @@
+// Package diff computes line diffs for file-editing tool calls.
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+const (
+	// contextLines is the number of unchanged lines shown around a change.
+	contextLines = 3
+	// maxCells limits the size of the LCS table; larger inputs are shown as a full replacement.
+	maxCells = 4_000_000
+)
+
+// LineKind is the kind of a diff line.
+type LineKind int
+
+const (
+	// Context is an unchanged line.
+	Context LineKind = iota
+	// Added is a line only in the new text.
+	Added
+	// Removed is a line only in the old text.
+	Removed
+	// HunkHeader is the "@@ -a,b +c,d @@" line starting a hunk.
+	HunkHeader
+)
+
+// Line is a single line of a laid-out diff.
+type Line struct {
+	Kind  LineKind
+	OldNo int // line number in the old text (0 = none)
+	NewNo int // line number in the new text (0 = none)
+	Text  string
+}
+
+// IsEditTool returns whether a tool edits files and is shown as a diff.
+func IsEditTool(name string) bool {
+	switch name {
+	case "Edit", "MultiEdit", "Write":
+		return true
+	default:
+		return false
+	}
+}
+
+// ForToolUse returns the file path and hunks of a file-editing tool call.
+// The structured patch recorded in the tool result is used when available;
+// otherwise the diff is computed from the tool input, with line numbers relative to the edited snippet.
+func ForToolUse(block parser.ContentBlock, result *parser.ToolUseResult) (string, []parser.PatchHunk) {
+	input, _ := block.Input.(map[string]any)
+	path := stringField(input, "file_path")
+	if result != nil && result.FilePath != "" {
+		path = result.FilePath
+	}
+
+	if result != nil && len(result.StructuredPatch) > 0 {
+		return path, result.StructuredPatch
+	}
+
+	switch block.Name {
+	case "Edit":
+		return path, Compute(stringField(input, "old_string"), stringField(input, "new_string"))
+	case "MultiEdit":
+		var hunks []parser.PatchHunk
+		edits, _ := input["edits"].([]any)
+		for _, e := range edits {
+			edit, _ := e.(map[string]any)
+			hunks = append(hunks, Compute(stringField(edit, "old_string"), stringField(edit, "new_string"))...)
+		}
+
+		return path, hunks
+	case "Write":
+		return path, Compute("", stringField(input, "content"))
+	default:
+		return path, nil
+	}
+}
+
+func stringField(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+
+	return s
+}
+
+// op is a single step of an edit script.
+type op struct {
+	kind LineKind
+	text string
+}
+
+// Compute returns the hunks turning oldText into newText, with a few lines of context.
+func Compute(oldText, newText string) []parser.PatchHunk {
+	ops := editScript(splitLines(oldText), splitLines(newText))
+
+	// Find the ranges of ops to show: changes plus context, merging nearby ranges.
+	type span struct{ from, to int }
+	var spans []span
+	for i, o := range ops {
+		if o.kind == Context {
+			continue
+		}
+		from, to := max(0, i-contextLines), min(len(ops), i+contextLines+1)
+		if len(spans) > 0 && from <= spans[len(spans)-1].to {
+			spans[len(spans)-1].to = to
+
+			continue
+		}
+		spans = append(spans, span{from, to})
+	}
+
+	hunks := make([]parser.PatchHunk, 0, len(spans))
+	oldNo, newNo, pos := 1, 1, 0
+	for _, sp := range spans {
+		// Advance line numbers to the start of the span.
+		for ; pos < sp.from; pos++ {
+			oldNo, newNo = advance(ops[pos].kind, oldNo, newNo)
+		}
+
+		hunk := parser.PatchHunk{OldStart: oldNo, NewStart: newNo}
+		for ; pos < sp.to; pos++ {
+			o := ops[pos]
+			switch o.kind {
+			case Added:
+				hunk.Lines = append(hunk.Lines, "+"+o.text)
+				hunk.NewLines++
+			case Removed:
+				hunk.Lines = append(hunk.Lines, "-"+o.text)
+				hunk.OldLines++
+			default:
+				hunk.Lines = append(hunk.Lines, " "+o.text)
+				hunk.OldLines++
+				hunk.NewLines++
+			}
+			oldNo, newNo = advance(o.kind, oldNo, newNo)
+		}
+		// By convention an empty side starts at the line before the hunk (e.g. "-0,0" for a new file).
+		if hunk.OldLines == 0 {
+			hunk.OldStart--
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart--
+		}
+		hunks = append(hunks, hunk)
+	}
+
+	return hunks
+}
+
+// advance returns the line numbers following a line of the given kind.
+func advance(kind LineKind, oldNo, newNo int) (int, int) {
+	switch kind {
+	case Added:
+		return oldNo, newNo + 1
+	case Removed:
+		return oldNo + 1, newNo
+	default:
+		return oldNo + 1, newNo + 1
+	}
+}
+
+// splitLines splits text into lines, ignoring a trailing newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// editScript returns the operations turning a into b, based on the longest common subsequence.
+// Inputs too large for the LCS table are treated as a full replacement.
+func editScript(a, b []string) []op {
+	ops := make([]op, 0, len(a)+len(b))
+	if len(a)*len(b) > maxCells {
+		for _, line := range a {
+			ops = append(ops, op{Removed, line})
+		}
+		for _, line := range b {
+			ops = append(ops, op{Added, line})
+		}
+
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{Context, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{Removed, a[i]})
+			i++
+		default:
+			ops = append(ops, op{Added, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{Removed, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{Added, b[j]})
+	}
+
+	return ops
+}
+
+// Lines lays out hunks as numbered diff lines.
+func Lines(hunks []parser.PatchHunk) []Line {
+	var lines []Line
+	for _, hunk := range hunks {
+		lines = append(lines, Line{
+			Kind: HunkHeader,
+			Text: fmt.Sprintf("@@ -%d,%d +%d,%d @@", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines),
+		})
+
+		oldNo, newNo := hunk.OldStart, hunk.NewStart
+		for _, text := range hunk.Lines {
+			if text == "" {
+				text = " "
+			}
+			switch text[0] {
+			case '+':
+				lines = append(lines, Line{Kind: Added, NewNo: newNo, Text: text[1:]})
+				newNo++
+			case '-':
+				lines = append(lines, Line{Kind: Removed, OldNo: oldNo, Text: text[1:]})
+				oldNo++
+			case '\\':
+				// "\ No newline at end of file".
+				lines = append(lines, Line{Kind: Context, Text: text})
+			default:
+				lines = append(lines, Line{Kind: Context, OldNo: oldNo, NewNo: newNo, Text: text[1:]})
+				oldNo++
+				newNo++
+			}
+		}
+	}
+
+	return lines
+}
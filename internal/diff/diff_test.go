@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+// numbered returns the lines "line 1" to "line n".
+func numbered(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+
+	return lines
+}
+
+// text joins lines into a text ending in a newline.
+func text(lines []string) string {
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestCompute(t *testing.T) {
+	changed := numbered(20)
+	changed[9] = "changed 10"
+	inserted := slices.Insert(numbered(20), 5, "new")
+	apart := numbered(20)
+	apart[1] = "changed 2"
+	apart[17] = "changed 18"
+
+	tests := []struct {
+		name     string
+		old, new string
+		want     []parser.PatchHunk
+	}{
+		{name: "same", old: "a\nb\n", new: "a\nb\n", want: []parser.PatchHunk{}},
+		{
+			name: "new file", old: "", new: "a\nb\n",
+			want: []parser.PatchHunk{{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 2, Lines: []string{"+a", "+b"}}},
+		},
+		{
+			name: "emptied file", old: "a\nb", new: "",
+			want: []parser.PatchHunk{{OldStart: 1, OldLines: 2, NewStart: 0, NewLines: 0, Lines: []string{"-a", "-b"}}},
+		},
+		{
+			name: "changed line with context", old: text(numbered(20)), new: text(changed),
+			want: []parser.PatchHunk{{
+				OldStart: 7, OldLines: 7, NewStart: 7, NewLines: 7,
+				Lines: []string{" line 7", " line 8", " line 9", "-line 10", "+changed 10", " line 11", " line 12", " line 13"},
+			}},
+		},
+		{
+			name: "inserted line", old: text(numbered(20)), new: text(inserted),
+			want: []parser.PatchHunk{{
+				OldStart: 3, OldLines: 6, NewStart: 3, NewLines: 7,
+				Lines: []string{" line 3", " line 4", " line 5", "+new", " line 6", " line 7", " line 8"},
+			}},
+		},
+		{
+			name: "changes far apart", old: text(numbered(20)), new: text(apart),
+			want: []parser.PatchHunk{
+				{
+					OldStart: 1, OldLines: 5, NewStart: 1, NewLines: 5,
+					Lines: []string{" line 1", "-line 2", "+changed 2", " line 3", " line 4", " line 5"},
+				},
+				{
+					OldStart: 15, OldLines: 6, NewStart: 15, NewLines: 6,
+					Lines: []string{" line 15", " line 16", " line 17", "-line 18", "+changed 18", " line 19", " line 20"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(tt.old, tt.new)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("Compute() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	hunks := []parser.PatchHunk{{
+		OldStart: 10, OldLines: 3, NewStart: 12, NewLines: 3,
+		Lines: []string{" a", "-b", "+B", "+C", "", `\ No newline at end of file`},
+	}}
+
+	want := []Line{
+		{Kind: HunkHeader, Text: "@@ -10,3 +12,3 @@"},
+		{Kind: Context, OldNo: 10, NewNo: 12, Text: "a"},
+		{Kind: Removed, OldNo: 11, Text: "b"},
+		{Kind: Added, NewNo: 13, Text: "B"},
+		{Kind: Added, NewNo: 14, Text: "C"},
+		{Kind: Context, OldNo: 12, NewNo: 15, Text: ""},
+		{Kind: Context, Text: `\ No newline at end of file`},
+	}
+	if got := Lines(hunks); !slices.Equal(got, want) {
+		t.Fatalf("Lines() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestForToolUse(t *testing.T) {
+	edit := parser.ContentBlock{Type: "tool_use", Name: "Edit", Input: map[string]any{
+		"file_path": "a.go", "old_string": "x\ny", "new_string": "x\nz",
+	}}
+	recorded := []parser.PatchHunk{{OldStart: 40, OldLines: 1, NewStart: 40, NewLines: 1, Lines: []string{"-y", "+z"}}}
+
+	tests := []struct {
+		name     string
+		block    parser.ContentBlock
+		result   *parser.ToolUseResult
+		wantPath string
+		want     []parser.PatchHunk
+	}{
+		{
+			name: "edit input", block: edit, wantPath: "a.go",
+			want: []parser.PatchHunk{{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2, Lines: []string{" x", "-y", "+z"}}},
+		},
+		{
+			name: "recorded patch", block: edit, wantPath: "/src/a.go",
+			result: &parser.ToolUseResult{FilePath: "/src/a.go", StructuredPatch: recorded},
+			want:   recorded,
+		},
+		{
+			name: "multi edit",
+			block: parser.ContentBlock{Type: "tool_use", Name: "MultiEdit", Input: map[string]any{
+				"file_path": "b.go",
+				"edits": []any{
+					map[string]any{"old_string": "a", "new_string": "b"},
+					map[string]any{"old_string": "c", "new_string": ""},
+				},
+			}},
+			wantPath: "b.go",
+			want: []parser.PatchHunk{
+				{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1, Lines: []string{"-a", "+b"}},
+				{OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0, Lines: []string{"-c"}},
+			},
+		},
+		{
+			name:     "write",
+			block:    parser.ContentBlock{Type: "tool_use", Name: "Write", Input: map[string]any{"file_path": "c.go", "content": "a\n"}},
+			wantPath: "c.go",
+			want:     []parser.PatchHunk{{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []string{"+a"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, got := ForToolUse(tt.block, tt.result)
+			if path != tt.wantPath {
+				t.Errorf("path = %q, want %q", path, tt.wantPath)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("hunks =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
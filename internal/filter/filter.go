@@ -0,0 +1,188 @@
+// Package filter selects which message blocks are displayed.
+package filter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+// Kind is a kind of displayed block.
+type Kind int
+
+const (
+	// KindUser is a prompt typed by the user.
+	KindUser Kind = iota
+	// KindText is assistant text.
+	KindText
+	// KindThinking is assistant thinking.
+	KindThinking
+	// KindToolUse is a tool call.
+	KindToolUse
+	// KindToolResult is the result of a tool call.
+	KindToolResult
+)
+
+// Kinds lists all block kinds in display order.
+var Kinds = []Kind{KindUser, KindText, KindThinking, KindToolUse, KindToolResult} //nolint:gochecknoglobals // package-level config
+
+// String returns the short name of the kind, as used in labels and headers.
+func (k Kind) String() string {
+	switch k {
+	case KindUser:
+		return "user"
+	case KindText:
+		return "text"
+	case KindThinking:
+		return "think"
+	case KindToolUse:
+		return "tool"
+	case KindToolResult:
+		return "result"
+	default:
+		return "unknown"
+	}
+}
+
+// KindOf returns the kind of a block in a message of the given type.
+// Tool results arrive in user messages and are reported as KindToolResult.
+func KindOf(msgType string, block parser.ContentBlock) Kind {
+	switch {
+	case block.Type == "tool_result":
+		return KindToolResult
+	case msgType == "user":
+		return KindUser
+	case block.Type == "thinking":
+		return KindThinking
+	case block.Type == "tool_use":
+		return KindToolUse
+	default:
+		return KindText
+	}
+}
+
+// Filter holds which block kinds and tool names are hidden.
+// The zero value shows everything.
+type Filter struct {
+	hiddenKinds map[Kind]bool
+	hiddenTools map[string]bool
+}
+
+// New creates a filter that shows everything.
+func New() *Filter {
+	return &Filter{
+		hiddenKinds: make(map[Kind]bool),
+		hiddenTools: make(map[string]bool),
+	}
+}
+
+// ToggleKind shows or hides a block kind.
+func (f *Filter) ToggleKind(k Kind) {
+	f.hiddenKinds[k] = !f.hiddenKinds[k]
+}
+
+// KindHidden returns whether a block kind is hidden.
+func (f *Filter) KindHidden(k Kind) bool {
+	return f.hiddenKinds[k]
+}
+
+// ToggleTool shows or hides calls to a tool and their results.
+func (f *Filter) ToggleTool(name string) {
+	if f.hiddenTools[name] {
+		delete(f.hiddenTools, name)
+
+		return
+	}
+	f.hiddenTools[name] = true
+}
+
+// ToolHidden returns whether calls to a tool are hidden.
+func (f *Filter) ToolHidden(name string) bool {
+	return f.hiddenTools[name]
+}
+
+// HiddenTools returns the hidden tool names in sorted order.
+func (f *Filter) HiddenTools() []string {
+	names := make([]string, 0, len(f.hiddenTools))
+	for name := range f.hiddenTools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Reset shows everything.
+func (f *Filter) Reset() {
+	f.hiddenKinds = make(map[Kind]bool)
+	f.hiddenTools = make(map[string]bool)
+}
+
+// Active returns whether anything is hidden.
+func (f *Filter) Active() bool {
+	for _, hidden := range f.hiddenKinds {
+		if hidden {
+			return true
+		}
+	}
+
+	return len(f.hiddenTools) > 0
+}
+
+// Allows returns whether a block is shown.
+// toolName is the tool of a tool_use block, or the tool a tool_result answers (empty if unknown).
+func (f *Filter) Allows(kind Kind, toolName string) bool {
+	if f == nil {
+		return true
+	}
+	if f.hiddenKinds[kind] {
+		return false
+	}
+	if (kind == KindToolUse || kind == KindToolResult) && toolName != "" && f.hiddenTools[toolName] {
+		return false
+	}
+
+	return true
+}
+
+// String summarizes the hidden kinds and tools for headers, e.g. "-think -result -Bash".
+// It returns an empty string when nothing is hidden.
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, k := range Kinds {
+		if f.hiddenKinds[k] {
+			parts = append(parts, "-"+k.String())
+		}
+	}
+	for _, name := range f.HiddenTools() {
+		parts = append(parts, "-"+name)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ToolNames tracks tool names by tool_use ID so that results can be matched to their tool.
+type ToolNames map[string]string
+
+// Observe records the tool names of the tool_use blocks in a message.
+func (t ToolNames) Observe(msg parser.Message) {
+	for _, block := range msg.Message.Content {
+		if block.Type == "tool_use" && block.ID != "" {
+			t[block.ID] = block.Name
+		}
+	}
+}
+
+// Name returns the tool name of a tool_use or tool_result block.
+func (t ToolNames) Name(block parser.ContentBlock) string {
+	if block.Type == "tool_use" {
+		return block.Name
+	}
+
+	return t[block.ToolUseID]
+}
@@ -0,0 +1,103 @@
+package filter
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		msgType string
+		block   string
+		want    Kind
+	}{
+		{msgType: "user", block: "text", want: KindUser},
+		{msgType: "user", block: "tool_result", want: KindToolResult},
+		{msgType: "assistant", block: "text", want: KindText},
+		{msgType: "assistant", block: "thinking", want: KindThinking},
+		{msgType: "assistant", block: "tool_use", want: KindToolUse},
+		{msgType: "assistant", block: "image", want: KindText},
+	}
+
+	for _, tt := range tests {
+		if got := KindOf(tt.msgType, parser.ContentBlock{Type: tt.block}); got != tt.want {
+			t.Errorf("KindOf(%q, %q) = %v, want %v", tt.msgType, tt.block, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := New()
+	if f.Active() || f.String() != "" {
+		t.Fatalf("new filter is active: %q", f.String())
+	}
+
+	f.ToggleKind(KindToolResult)
+	f.ToggleKind(KindThinking)
+	f.ToggleTool("Read")
+	f.ToggleTool("Bash")
+	if got := f.String(); got != "-think -result -Bash -Read" {
+		t.Errorf("String() = %q", got)
+	}
+	if !f.KindHidden(KindThinking) || f.KindHidden(KindText) {
+		t.Errorf("hidden kinds are wrong: %q", f.String())
+	}
+	if got := f.HiddenTools(); !slices.Equal(got, []string{"Bash", "Read"}) {
+		t.Errorf("HiddenTools() = %q", got)
+	}
+
+	tests := []struct {
+		kind Kind
+		tool string
+		want bool
+	}{
+		{kind: KindText, want: true},
+		{kind: KindThinking, want: false},
+		{kind: KindToolUse, tool: "Bash", want: false},
+		{kind: KindToolUse, tool: "Edit", want: true},
+		{kind: KindToolUse, want: true},
+		{kind: KindToolResult, tool: "Edit", want: false},
+		{kind: KindUser, tool: "Bash", want: true},
+	}
+	for _, tt := range tests {
+		if got := f.Allows(tt.kind, tt.tool); got != tt.want {
+			t.Errorf("Allows(%v, %q) = %v, want %v", tt.kind, tt.tool, got, tt.want)
+		}
+	}
+
+	f.ToggleKind(KindThinking)
+	f.ToggleTool("Read")
+	if got := f.String(); got != "-result -Bash" {
+		t.Errorf("String() after toggling back = %q", got)
+	}
+
+	f.Reset()
+	if f.Active() || f.String() != "" || !f.Allows(KindToolUse, "Bash") {
+		t.Errorf("filter after Reset() = %q", f.String())
+	}
+
+	var none *Filter
+	if !none.Allows(KindThinking, "Bash") || none.String() != "" {
+		t.Errorf("nil filter hides blocks")
+	}
+}
+
+func TestToolNames(t *testing.T) {
+	names := ToolNames{}
+	names.Observe(parser.Message{Message: parser.MessageContent{Content: []parser.ContentBlock{
+		{Type: "text", Text: "running"},
+		{Type: "tool_use", ID: "t1", Name: "Bash"},
+	}}})
+
+	if got := names.Name(parser.ContentBlock{Type: "tool_result", ToolUseID: "t1"}); got != "Bash" {
+		t.Errorf("name of an answered result = %q", got)
+	}
+	if got := names.Name(parser.ContentBlock{Type: "tool_result", ToolUseID: "t2"}); got != "" {
+		t.Errorf("name of an unknown result = %q", got)
+	}
+	if got := names.Name(parser.ContentBlock{Type: "tool_use", Name: "Edit"}); got != "Edit" {
+		t.Errorf("name of a call = %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+// Package fuzzy matches short patterns against text the way command palettes do:
+// the characters of the pattern must appear in order, not necessarily adjacent.
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Scoring of a match. Matches at word starts and runs of adjacent characters rank first.
+const (
+	scoreMatch     = 1
+	bonusAdjacent  = 4
+	bonusWordStart = 6
+	bonusFirstRune = 2
+	penaltyGap     = 1
+	maxGapPenalty  = 8
+)
+
+// Match reports whether every space-separated term of pattern matches text as a subsequence.
+// Matching is case-insensitive. The score ranks better matches higher and positions holds
+// the indexes of the matched runes of text, in order.
+func Match(pattern, text string) (int, []int, bool) {
+	terms := strings.Fields(strings.ToLower(pattern))
+	if len(terms) == 0 {
+		return 0, nil, true
+	}
+
+	// Runes are lowered one by one so that positions index the runes of text.
+	runes := []rune(text)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	matched := make([]bool, len(runes))
+	score := 0
+	for _, term := range terms {
+		termScore, ok := matchTerm([]rune(term), runes, matched)
+		if !ok {
+			return 0, nil, false
+		}
+		score += termScore
+	}
+
+	var positions []int
+	for i, ok := range matched {
+		if ok {
+			positions = append(positions, i)
+		}
+	}
+
+	return score, positions, true
+}
+
+// matchTerm matches one term against the lower-cased text, marking the matched runes.
+// Each term is tried from every occurrence of its first rune and the best start wins.
+func matchTerm(term, text []rune, matched []bool) (int, bool) {
+	bestScore := -1
+	var best []int
+	for start := range text {
+		if text[start] != term[0] {
+			continue
+		}
+		score, positions, ok := matchFrom(term, text, start)
+		if ok && score > bestScore {
+			bestScore = score
+			best = positions
+		}
+	}
+	if bestScore < 0 {
+		return 0, false
+	}
+
+	for _, i := range best {
+		matched[i] = true
+	}
+
+	return bestScore, true
+}
+
+// matchFrom matches term greedily against text starting at the given index.
+func matchFrom(term, text []rune, start int) (int, []int, bool) {
+	score := -min(start, maxGapPenalty) * penaltyGap
+	positions := make([]int, 0, len(term))
+	prev := -1
+	j := 0
+	for i := start; i < len(text) && j < len(term); i++ {
+		if text[i] != term[j] {
+			continue
+		}
+		score += scoreMatch
+		switch {
+		case prev >= 0 && i == prev+1:
+			score += bonusAdjacent
+		case prev >= 0:
+			score -= min(i-prev-1, maxGapPenalty) * penaltyGap
+		}
+		if i == 0 {
+			score += bonusFirstRune
+		}
+		if isWordStart(text, i) {
+			score += bonusWordStart
+		}
+		positions = append(positions, i)
+		prev = i
+		j++
+	}
+
+	return score, positions, j == len(term)
+}
+
+// isWordStart reports whether the rune at i starts a word: it follows a separator such as a space, slash or dash.
+func isWordStart(text []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := text[i-1]
+
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev)
+}
@@ -6,25 +6,100 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 )
 
 // ContentBlock represents a single content block in a message.
 type ContentBlock struct {
-	Type     string `json:"type"` // "thinking", "text", "tool_use", "tool_result"
-	Text     string `json:"text,omitempty"`
-	Thinking string `json:"thinking,omitempty"`
-	Name     string `json:"name,omitempty"`  // tool name
-	Input    any    `json:"input,omitempty"` // tool input
+	Type      string        `json:"type"` // "thinking", "text", "tool_use", "tool_result"
+	Text      string        `json:"text,omitempty"`
+	Thinking  string        `json:"thinking,omitempty"`
+	ID        string        `json:"id,omitempty"`          // tool_use ID
+	Name      string        `json:"name,omitempty"`        // tool name
+	Input     any           `json:"input,omitempty"`       // tool input
+	ToolUseID string        `json:"tool_use_id,omitempty"` // tool_use ID a tool_result answers
+	Content   ResultContent `json:"content,omitempty"`     // tool_result content
+	IsError   bool          `json:"is_error,omitempty"`    // tool_result reported an error
+}
+
+// ResultText returns the text of a tool_result block.
+func (b ContentBlock) ResultText() string {
+	if b.Content != "" {
+		return string(b.Content)
+	}
+
+	return b.Text
+}
+
+// ResultContent is the text of a tool_result block.
+// The content can be either a string or an array of text blocks.
+type ResultContent string
+
+// UnmarshalJSON handles both string and array content.
+func (c *ResultContent) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*c = ResultContent(str)
+
+		return nil
+	}
+
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		// Unknown shape; ignore rather than failing the whole message.
+		return nil //nolint:nilerr // tolerate unexpected content
+	}
+
+	texts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Text != "" {
+			texts = append(texts, block.Text)
+		}
+	}
+	*c = ResultContent(strings.Join(texts, "\n"))
+
+	return nil
 }
 
 // MessageContent represents the content of a message.
 // Content can be either a string or an array of ContentBlocks.
 type MessageContent struct {
-	Content []ContentBlock
+	Content    []ContentBlock
+	ID         string // API message ID; repeated on each content block of one assistant reply
+	StopReason string // why the assistant stopped, e.g. "end_turn" or "tool_use"
+	Usage      *Usage // token usage of an assistant reply
+}
+
+// Usage is the token usage reported for an assistant reply.
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// Total returns the input, cache and output tokens together.
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
 }
 
 // UnmarshalJSON handles both string and array content.
 func (m *MessageContent) UnmarshalJSON(data []byte) error {
+	var meta struct {
+		ID         string `json:"id"`
+		StopReason string `json:"stop_reason"`
+		Usage      *Usage `json:"usage"`
+	}
+	if err := json.Unmarshal(data, &meta); err == nil {
+		m.ID = meta.ID
+		m.StopReason = meta.StopReason
+		m.Usage = meta.Usage
+	}
+
 	// Try to unmarshal as a struct with content array first.
 	var structured struct {
 		Content []ContentBlock `json:"content"`
@@ -71,11 +146,102 @@ func (m *MessageContent) UnmarshalJSON(data []byte) error {
 
 // Message represents a single message in a JSONL file.
 type Message struct {
-	Type      string         `json:"type"` // "user", "assistant", "system"
-	Message   MessageContent `json:"message"`
-	AgentID   string         `json:"agentId,omitempty"`
-	SessionID string         `json:"sessionId,omitempty"`
-	Timestamp string         `json:"timestamp"`
+	Type          string         `json:"type"` // "user", "assistant", "system"
+	Message       MessageContent `json:"message"`
+	AgentID       string         `json:"agentId,omitempty"`
+	SessionID     string         `json:"sessionId,omitempty"`
+	Timestamp     string         `json:"timestamp"`
+	ToolUseResult *ToolUseResult `json:"toolUseResult,omitempty"` // structured result of the tool_result in this message
+	CWD           string         `json:"cwd,omitempty"`           // working directory of the session
+	GitBranch     string         `json:"gitBranch,omitempty"`     // git branch checked out in the working directory
+	Summary       string         `json:"summary,omitempty"`       // session title, on "summary" lines
+
+	at time.Time // Timestamp parsed once by Parse; zero for messages built elsewhere
+}
+
+// ToolUseResult holds the structured result of a tool call.
+// Only the fields used for display are decoded.
+type ToolUseResult struct {
+	FilePath        string      `json:"filePath,omitempty"`
+	StructuredPatch []PatchHunk `json:"structuredPatch,omitempty"`
+	AgentID         string      `json:"agentId,omitempty"` // subagent started by a Task call
+}
+
+// UnmarshalJSON decodes an object result and ignores other shapes (e.g. error strings).
+func (r *ToolUseResult) UnmarshalJSON(data []byte) error {
+	type plain ToolUseResult
+	var result plain
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil //nolint:nilerr // tolerate unexpected results
+	}
+	*r = ToolUseResult(result)
+
+	return nil
+}
+
+// PatchHunk is a hunk of a unified diff, as recorded for file-editing tools.
+// Lines start with ' ', '-' or '+'.
+type PatchHunk struct {
+	OldStart int      `json:"oldStart"`
+	OldLines int      `json:"oldLines"`
+	NewStart int      `json:"newStart"`
+	NewLines int      `json:"newLines"`
+	Lines    []string `json:"lines"`
+}
+
+// Time returns the parsed timestamp of the message, or the zero time if it is missing or invalid.
+func (m Message) Time() time.Time {
+	if !m.at.IsZero() {
+		return m.at
+	}
+
+	return parseTime(m.Timestamp)
+}
+
+// parseTime parses a message timestamp, returning the zero time if it is missing or invalid.
+func parseTime(timestamp string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
+
+// PlainText returns the searchable text of the message:
+// text, thinking, tool names with their input, and tool results.
+func (m Message) PlainText() string {
+	parts := make([]string, 0, len(m.Message.Content))
+	for _, block := range m.Message.Content {
+		parts = append(parts, block.PlainText())
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// PlainText returns the searchable text of the block: its text or thinking,
+// a tool name with its input, or a tool result.
+func (b ContentBlock) PlainText() string {
+	switch b.Type {
+	case "thinking":
+		return b.Thinking + "\n" + b.Text
+	case "tool_use":
+		if b.Input == nil {
+			return b.Name
+		}
+		var buf strings.Builder
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(b.Input); err != nil {
+			return b.Name
+		}
+
+		return b.Name + "\n" + buf.String()
+	case "tool_result":
+		return b.ResultText()
+	default:
+		return b.Text
+	}
 }
 
 // ParseFile reads a JSONL file and returns all messages.
@@ -109,6 +275,8 @@ func Parse(r io.Reader) ([]Message, error) {
 			// Skip malformed lines.
 			continue
 		}
+		// Timestamps are read on every render; parse them once.
+		msg.at = parseTime(msg.Timestamp)
 		messages = append(messages, msg)
 	}
 
@@ -0,0 +1,198 @@
+package render
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+// Cache keeps what was rendered of one session between renders, so that rendering the session again
+// only takes in the messages added since and renders only the messages that are new or changed.
+// The tool results, call durations and turn ends of the session are folded in as messages arrive;
+// a message is rendered again when one of them changes it, when its relative time label changes
+// (tracked by when each label next changes, so that aging does not walk every message),
+// or when it is marked with Invalidate. Changing the width, the options or the styles renders everything again.
+// Use a cache for a single session; one that is handed another session starts over.
+type Cache struct {
+	styles    *Styles // styles the entries were rendered with
+	options   optionsKey
+	state     running
+	entries   []entry      // by message index
+	stale     map[int]bool // messages to render again
+	expiries  expiries     // when the relative time labels of rendered messages change
+	doc       Document     // document assembled from the entries
+	unchanged int          // leading lines of doc that are the same as in the document assembled before it
+}
+
+// entry is the rendered lines of one message.
+type entry struct {
+	expires time.Time // when its relative time label changes; zero = never
+	lines   []Line
+	ranges  []BlockRange // Start and End relative to the first line of the entry
+}
+
+// expiry is when the relative time label of a rendered message changes.
+type expiry struct {
+	at  time.Time
+	msg int
+}
+
+// expiries is a min-heap of expiries by time.
+type expiries []expiry
+
+func (x expiries) Len() int           { return len(x) }
+func (x expiries) Less(i, j int) bool { return x[i].at.Before(x[j].at) }
+func (x expiries) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+
+func (x *expiries) Push(v any) {
+	*x = append(*x, v.(expiry)) //nolint:forcetypeassert // only expiries are pushed
+}
+
+func (x *expiries) Pop() any {
+	old := *x
+	v := old[len(old)-1]
+	*x = old[:len(old)-1]
+
+	return v
+}
+
+// optionsKey holds the options that apply to every message; rendered entries are dropped when they change.
+type optionsKey struct {
+	width        int
+	maxDiffLines int
+	markdown     bool
+	timestamps   TimestampMode
+	idleGap      time.Duration
+	filter       string // hidden kinds and tools
+}
+
+// NewCache creates an empty cache.
+func NewCache() *Cache {
+	return &Cache{state: newRunning(), stale: make(map[int]bool)}
+}
+
+// Reset drops everything, e.g. when another session is shown.
+func (c *Cache) Reset() {
+	c.state = newRunning()
+	c.InvalidateAll()
+}
+
+// Invalidate marks a message to be rendered again, e.g. after the display state of one of its blocks changed.
+func (c *Cache) Invalidate(msg int) {
+	if msg < len(c.entries) {
+		c.stale[msg] = true
+	}
+}
+
+// InvalidateAll marks every message to be rendered again, e.g. after the display state of many blocks changed.
+// What was folded in from the messages is kept.
+func (c *Cache) InvalidateAll() {
+	c.entries = nil
+	c.stale = make(map[int]bool)
+	c.expiries = nil
+	c.doc = Document{}
+	c.unchanged = 0
+}
+
+// Unchanged returns how many leading lines of the last rendered document are the same as
+// in the document rendered before it with this cache.
+func (c *Cache) Unchanged() int {
+	return c.unchanged
+}
+
+// begin prepares the cache for rendering messages with the given styles and options,
+// and folds in the messages added since the last render.
+func (c *Cache) begin(styles *Styles, messages []parser.Message, opts Options) {
+	if !c.state.continues(messages) {
+		// Another session, or the session was replaced.
+		c.Reset()
+	}
+	options := optionsKey{
+		width:        opts.Width,
+		maxDiffLines: opts.MaxDiffLines,
+		markdown:     opts.Markdown,
+		timestamps:   opts.Timestamps,
+		idleGap:      opts.IdleGap,
+		filter:       opts.Filter.String(),
+	}
+	if c.styles != styles || c.options != options {
+		c.styles = styles
+		c.options = options
+		c.InvalidateAll()
+	}
+
+	for _, msg := range messages[c.state.messages:] {
+		for _, i := range c.state.add(msg) {
+			c.Invalidate(i)
+		}
+	}
+}
+
+// age marks the messages whose relative time label changed by now.
+func (c *Cache) age(now time.Time) {
+	for len(c.expiries) > 0 && !c.expiries[0].at.After(now) {
+		x := heap.Pop(&c.expiries).(expiry) //nolint:forcetypeassert // only expiries are pushed
+		// An entry rendered again since has an expiry of its own.
+		if x.msg < len(c.entries) && c.entries[x.msg].expires.Equal(x.at) {
+			c.stale[x.msg] = true
+		}
+	}
+}
+
+// first returns the index of the first message to render, or the number of rendered messages if none changed.
+func (c *Cache) first() int {
+	first := len(c.entries)
+	for i := range c.stale {
+		first = min(first, i)
+	}
+
+	return first
+}
+
+// rendered returns whether message i was rendered and has not changed since.
+func (c *Cache) rendered(i int) bool {
+	return i < len(c.entries) && !c.stale[i]
+}
+
+// put stores the entry of message i.
+func (c *Cache) put(i int, e entry) {
+	delete(c.stale, i)
+	if !e.expires.IsZero() {
+		heap.Push(&c.expiries, expiry{at: e.expires, msg: i})
+	}
+	if i < len(c.entries) {
+		c.entries[i] = e
+
+		return
+	}
+	c.entries = append(c.entries, e)
+}
+
+// assemble updates the document from message from onwards and returns it.
+// The document shares its lines with the cache; it is valid until the next render.
+func (c *Cache) assemble(from int) Document {
+	start := len(c.doc.Lines)
+	if from < len(c.doc.MsgStarts) {
+		start = c.doc.MsgStarts[from]
+	}
+	blocks := sort.Search(len(c.doc.Blocks), func(k int) bool { return c.doc.Blocks[k].Msg >= from })
+	c.doc.Lines = c.doc.Lines[:start]
+	c.doc.MsgStarts = c.doc.MsgStarts[:from]
+	c.doc.Blocks = c.doc.Blocks[:blocks]
+	c.unchanged = start
+
+	for _, e := range c.entries[from:] {
+		start := len(c.doc.Lines)
+		c.doc.MsgStarts = append(c.doc.MsgStarts, start)
+		c.doc.Lines = append(c.doc.Lines, e.lines...)
+		for _, b := range e.ranges {
+			b.Start += start
+			b.End += start
+			c.doc.Blocks = append(c.doc.Blocks, b)
+		}
+	}
+
+	return c.doc
+}
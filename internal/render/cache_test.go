@@ -0,0 +1,191 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sters/cc-session-tailing/internal/filter"
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+// testStart is the time of the first message of testSession.
+var testStart = time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC) //nolint:gochecknoglobals // test fixture
+
+// testSession returns a session of a few turns with tool calls answered later, turns ended by
+// "end_turn" or by the next prompt, and an idle gap.
+func testSession() []parser.Message {
+	var messages []parser.Message
+	at := testStart
+	add := func(msgType string, d time.Duration, stop string, blocks ...parser.ContentBlock) {
+		messages = append(messages, parser.Message{
+			Type:      msgType,
+			Timestamp: at.Format(time.RFC3339Nano),
+			Message:   parser.MessageContent{Content: blocks, StopReason: stop},
+		})
+		at = at.Add(d)
+	}
+
+	for turn := range 4 {
+		add("user", 5*time.Second, "", parser.ContentBlock{Type: "text", Text: fmt.Sprintf("prompt %d", turn)})
+		add("assistant", 3*time.Second, "",
+			parser.ContentBlock{Type: "thinking", Thinking: strings.Repeat("thinking hard ", 20)},
+			parser.ContentBlock{Type: "text", Text: "Let me check"})
+		for call := range 2 {
+			id := fmt.Sprintf("t%d_%d", turn, call)
+			block := parser.ContentBlock{Type: "tool_use", ID: id, Name: "Bash", Input: map[string]any{"command": "ls"}}
+			if call == 1 {
+				block.Name = "Edit"
+				block.Input = map[string]any{"file_path": "a.go", "old_string": "a\nb\nc\nd", "new_string": "a\nB\nC\nD"}
+			}
+			add("assistant", time.Duration(2+call*4)*time.Second, "", block)
+			add("user", time.Second, "",
+				parser.ContentBlock{Type: "tool_result", ToolUseID: id, Content: "output\nmore output"})
+		}
+		stop := ""
+		if turn%2 == 0 {
+			stop = "end_turn"
+		}
+		gap := 30 * time.Second
+		if turn == 1 {
+			gap = 10 * time.Minute
+		}
+		add("assistant", gap, stop, parser.ContentBlock{Type: "text", Text: fmt.Sprintf("Done with **turn %d**", turn)})
+	}
+
+	return messages
+}
+
+// dump returns the lines, message starts and block ranges of a document as text.
+func dump(doc Document) string {
+	var b strings.Builder
+	for _, line := range doc.Lines {
+		b.WriteString(line.String() + "\n")
+	}
+	fmt.Fprintln(&b, doc.MsgStarts)
+	fmt.Fprintln(&b, doc.Blocks)
+
+	return b.String()
+}
+
+func TestCacheMatchesFullRenderAsMessagesArrive(t *testing.T) {
+	messages := testSession()
+	r := NewRenderer(nil)
+
+	for _, mode := range []TimestampMode{TimestampsOff, TimestampsAbsolute, TimestampsRelative} {
+		t.Run(mode.String(), func(t *testing.T) {
+			cache := NewCache()
+			for n := 1; n <= len(messages); n++ {
+				// The clock runs ahead of the messages, so relative labels age between renders.
+				opts := Options{Width: 60, Timestamps: mode, Now: testStart.Add(time.Duration(n*n) * 7 * time.Second)}
+				want := dump(r.Session(messages[:n], opts))
+				opts.Cache = cache
+				if got := dump(r.Session(messages[:n], opts)); got != want {
+					t.Fatalf("after %d messages:\ngot:\n%s\nwant:\n%s", n, got, want)
+				}
+				if got := dump(r.Session(messages[:n], opts)); got != want {
+					t.Fatalf("after %d messages, rendered again:\ngot:\n%s\nwant:\n%s", n, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheRendersAgainWhenInputsChange(t *testing.T) {
+	messages := testSession()
+	base := Options{Width: 60, Timestamps: TimestampsAbsolute, Now: testStart, Filter: filter.New()}
+	expanded := map[[2]int]bool{}
+	state := func(msg, block int, _ parser.ContentBlock) BlockState {
+		return BlockState{Expanded: expanded[[2]int{msg, block}]}
+	}
+
+	tests := []struct {
+		name   string
+		change func(opts *Options, r **Renderer, cache *Cache)
+	}{
+		{name: "width", change: func(opts *Options, _ **Renderer, _ *Cache) { opts.Width = 40 }},
+		{name: "diff lines", change: func(opts *Options, _ **Renderer, _ *Cache) { opts.MaxDiffLines = 3 }},
+		{name: "markdown", change: func(opts *Options, _ **Renderer, _ *Cache) { opts.Markdown = true }},
+		{name: "timestamps", change: func(opts *Options, _ **Renderer, _ *Cache) { opts.Timestamps = TimestampsOff }},
+		{name: "idle gap", change: func(opts *Options, _ **Renderer, _ *Cache) { opts.IdleGap = time.Hour }},
+		{name: "hidden kind", change: func(opts *Options, _ **Renderer, _ *Cache) {
+			opts.Filter.ToggleKind(filter.KindThinking)
+		}},
+		{name: "hidden tool", change: func(opts *Options, _ **Renderer, _ *Cache) { opts.Filter.ToggleTool("Bash") }},
+		{name: "styles", change: func(_ *Options, r **Renderer, _ *Cache) { *r = NewRenderer(nil) }},
+		{name: "invalidated block", change: func(_ *Options, _ **Renderer, cache *Cache) {
+			expanded[[2]int{1, 0}] = true
+			cache.Invalidate(1)
+		}},
+		{name: "all invalidated", change: func(_ *Options, _ **Renderer, cache *Cache) {
+			expanded[[2]int{8, 0}] = true
+			cache.InvalidateAll()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clear(expanded)
+			r := NewRenderer(nil)
+			opts := base
+			opts.Filter = filter.New()
+			opts.State = state
+			cache := NewCache()
+			opts.Cache = cache
+			before := dump(r.Session(messages, opts))
+
+			tt.change(&opts, &r, cache)
+			got := dump(r.Session(messages, opts))
+			opts.Cache = nil
+			want := dump(r.Session(messages, opts))
+			if got != want {
+				t.Fatalf("cached render differs from a full render:\ngot:\n%s\nwant:\n%s", got, want)
+			}
+			if tt.name != "styles" && got == before {
+				t.Fatalf("change did not alter the document")
+			}
+		})
+	}
+}
+
+func TestCacheStartsOverForAnotherSession(t *testing.T) {
+	messages := testSession()
+	r := NewRenderer(nil)
+	cache := NewCache()
+	opts := Options{Width: 60, Cache: cache}
+	r.Session(messages, opts)
+
+	other := messages[3:9]
+	got := dump(r.Session(other, opts))
+	opts.Cache = nil
+	if want := dump(r.Session(other, opts)); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAgeChanges(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want time.Duration // age at which the label changes next
+	}{
+		{age: 0, want: 5 * time.Second},
+		{age: 4 * time.Second, want: 5 * time.Second},
+		{age: 5 * time.Second, want: 6 * time.Second},
+		{age: 59*time.Second + time.Millisecond, want: time.Minute},
+		{age: 90 * time.Second, want: 2 * time.Minute},
+		{age: 3*time.Hour + 5*time.Minute, want: 4 * time.Hour},
+		{age: 50 * time.Hour, want: 72 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		got := ageChanges(testStart, testStart.Add(tt.age)).Sub(testStart)
+		if got != tt.want {
+			t.Errorf("ageChanges at %v = %v, want %v", tt.age, got, tt.want)
+		}
+		before := formatAge(tt.want - time.Nanosecond)
+		if after := formatAge(tt.want); after == before {
+			t.Errorf("label %q does not change at %v", before, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/sters/cc-session-tailing/internal/diff"
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// DiffStyles holds styles for diff views.
+type DiffStyles struct {
+	File    lipgloss.Style
+	Hunk    lipgloss.Style
+	Added   lipgloss.Style
+	Removed lipgloss.Style
+	Context lipgloss.Style
+	LineNo  lipgloss.Style
+	More    lipgloss.Style
+}
+
+// NewDiffStyles creates the default diff styles.
+func NewDiffStyles() *DiffStyles {
+	c := theme.Colors()
+
+	return &DiffStyles{
+		File: lipgloss.NewStyle().
+			Foreground(c.Text).
+			Bold(true),
+		Hunk: lipgloss.NewStyle().
+			Foreground(c.Info),
+		Added: lipgloss.NewStyle().
+			Foreground(c.Success),
+		Removed: lipgloss.NewStyle().
+			Foreground(c.Error),
+		Context: lipgloss.NewStyle().
+			Foreground(c.Secondary),
+		LineNo: lipgloss.NewStyle().
+			Foreground(c.Muted),
+		More: lipgloss.NewStyle().
+			Foreground(c.Muted).
+			Italic(true),
+	}
+}
+
+// Diff renders a file header and diff lines fitted to width.
+// At most maxLines diff lines are shown (0 = no limit), followed by a count of the hidden lines.
+func Diff(path string, lines []diff.Line, width, maxLines int, styles *DiffStyles) []Line {
+	width = max(1, width)
+	ellipsis := theme.Symbols().Ellipsis
+	out := make([]Line, 0, len(lines)+2)
+	if path != "" {
+		out = append(out, NewLine(runewidth.Truncate(path, width, ellipsis), styles.File))
+	}
+
+	// Size the line number columns to the largest number shown.
+	largest := 0
+	for _, line := range lines {
+		largest = max(largest, line.OldNo, line.NewNo)
+	}
+	numWidth := len(fmt.Sprint(largest))
+
+	shown := lines
+	if maxLines > 0 && len(lines) > maxLines {
+		shown = lines[:maxLines]
+	}
+
+	for _, line := range shown {
+		if line.Kind == diff.HunkHeader {
+			out = append(out, NewLine(runewidth.Truncate(line.Text, width, ellipsis), styles.Hunk))
+
+			continue
+		}
+
+		gutter := fmt.Sprintf("%*s %*s ", numWidth, lineNo(line.OldNo), numWidth, lineNo(line.NewNo))
+		textWidth := max(1, width-runewidth.StringWidth(gutter)-1)
+		text := runewidth.Truncate(ExpandTabs(line.Text), textWidth, ellipsis)
+
+		body := Span{Text: " " + text, Style: styles.Context}
+		switch line.Kind {
+		case diff.Added:
+			body = Span{Text: "+" + text, Style: styles.Added}
+		case diff.Removed:
+			body = Span{Text: "-" + text, Style: styles.Removed}
+		}
+		out = append(out, Line{{Text: gutter, Style: styles.LineNo}, body}.Fit(width))
+	}
+
+	if hidden := len(lines) - len(shown); hidden > 0 {
+		out = append(out, NewLine(Fit(fmt.Sprintf("%s %d more diff lines", ellipsis, hidden), width), styles.More))
+	}
+
+	return out
+}
+
+func lineNo(n int) string {
+	if n == 0 {
+		return ""
+	}
+
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,119 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// keywords are highlighted in code blocks of any known language.
+// A shared set keeps the highlighter small; words that are keywords in only some languages are rare in others.
+var keywords = map[string]bool{ //nolint:gochecknoglobals // package-level config
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"def": true, "default": true, "defer": true, "do": true, "elif": true, "else": true,
+	"enum": true, "except": true, "export": true, "extends": true, "false": true, "fi": true,
+	"finally": true, "fn": true, "for": true, "from": true, "func": true, "function": true,
+	"go": true, "if": true, "impl": true, "import": true, "in": true, "interface": true,
+	"let": true, "map": true, "match": true, "mut": true, "new": true, "nil": true,
+	"None": true, "null": true, "package": true, "pub": true, "raise": true, "range": true,
+	"return": true, "select": true, "self": true, "static": true, "struct": true, "switch": true,
+	"then": true, "this": true, "throw": true, "True": true, "False": true, "true": true,
+	"try": true, "type": true, "use": true, "var": true, "while": true, "with": true,
+	"yield": true, "async": true, "await": true, "echo": true, "done": true, "esac": true,
+	"undefined": true, "lambda": true, "pass": true, "not": true, "and": true, "or": true,
+}
+
+// lineComments maps languages to their line comment prefix.
+var lineComments = map[string]string{ //nolint:gochecknoglobals // package-level config
+	"go": "//", "golang": "//", "js": "//", "javascript": "//", "jsx": "//", "ts": "//",
+	"typescript": "//", "tsx": "//", "java": "//", "kotlin": "//", "c": "//", "cpp": "//",
+	"c++": "//", "cs": "//", "csharp": "//", "rust": "//", "rs": "//", "swift": "//",
+	"scala": "//", "php": "//", "dart": "//", "zig": "//",
+	"sh": "#", "bash": "#", "zsh": "#", "shell": "#", "console": "#", "python": "#", "py": "#",
+	"ruby": "#", "rb": "#", "yaml": "#", "yml": "#", "toml": "#", "perl": "#", "r": "#",
+	"makefile": "#", "make": "#", "dockerfile": "#", "ini": ";",
+	"sql": "--", "lua": "--", "haskell": "--", "hs": "--",
+}
+
+// highlight colors a line of code in the given language.
+// Without a known language, the line is shown in the plain code style.
+func highlight(line, lang string, styles *MarkdownStyles) Line {
+	lang = strings.ToLower(lang)
+	comment, known := lineComments[lang]
+	if !known {
+		return NewLine(line, styles.Code)
+	}
+
+	var out Line
+	var plain strings.Builder
+	emit := func(text string, style lipgloss.Style) {
+		if plain.Len() > 0 {
+			out = append(out, Span{Text: plain.String(), Style: styles.Code})
+			plain.Reset()
+		}
+		out = append(out, Span{Text: text, Style: style})
+	}
+
+	for i := 0; i < len(line); {
+		rest := line[i:]
+		c := line[i]
+		switch {
+		case strings.HasPrefix(rest, comment):
+			emit(rest, styles.Comment)
+			i = len(line)
+
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			end := closingQuote(rest)
+			emit(rest[:end], styles.String)
+			i += end
+
+			continue
+		case c >= '0' && c <= '9' && (i == 0 || !isWordByte(line[i-1])):
+			end := 1
+			for end < len(rest) && (isWordByte(rest[end]) || rest[end] == '.') {
+				end++
+			}
+			emit(rest[:end], styles.Number)
+			i += end
+
+			continue
+		case isWordByte(c) && (i == 0 || !isWordByte(line[i-1])):
+			end := 1
+			for end < len(rest) && isWordByte(rest[end]) {
+				end++
+			}
+			if keywords[rest[:end]] {
+				emit(rest[:end], styles.Keyword)
+			} else {
+				plain.WriteString(rest[:end])
+			}
+			i += end
+
+			continue
+		}
+		plain.WriteByte(c)
+		i++
+	}
+	if plain.Len() > 0 {
+		out = append(out, Span{Text: plain.String(), Style: styles.Code})
+	}
+
+	return out
+}
+
+// closingQuote returns the length of the string literal at the start of text, including its quotes.
+// An unterminated literal extends to the end of the line.
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+
+	return len(text)
+}
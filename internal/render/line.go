@@ -0,0 +1,90 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// Span is a run of text rendered in a single style.
+type Span struct {
+	Text  string
+	Style lipgloss.Style
+}
+
+// Line is a display line made of styled spans. Span texts never contain line breaks.
+type Line []Span
+
+// NewLine creates a line with a single span.
+func NewLine(text string, style lipgloss.Style) Line {
+	return Line{{Text: text, Style: style}}
+}
+
+// Indent returns a line made of width spaces followed by the spans of l.
+func Indent(width int, l Line) Line {
+	return append(Line{{Text: strings.Repeat(" ", width)}}, l...)
+}
+
+// String returns the line with styles applied.
+func (l Line) String() string {
+	var b strings.Builder
+	for _, span := range l {
+		if span.Text == "" {
+			continue
+		}
+		b.WriteString(span.Style.Render(span.Text))
+	}
+
+	return b.String()
+}
+
+// Plain returns the text of the line without styles.
+func (l Line) Plain() string {
+	var b strings.Builder
+	for _, span := range l {
+		b.WriteString(span.Text)
+	}
+
+	return b.String()
+}
+
+// Width returns the display width of the line.
+func (l Line) Width() int {
+	width := 0
+	for _, span := range l {
+		width += runewidth.StringWidth(span.Text)
+	}
+
+	return width
+}
+
+// Fit truncates the line to width.
+func (l Line) Fit(width int) Line {
+	if l.Width() <= width {
+		return l
+	}
+
+	fitted := make(Line, 0, len(l))
+	remaining := width
+	for _, span := range l {
+		if remaining <= 0 {
+			break
+		}
+		text := Fit(span.Text, remaining)
+		remaining -= runewidth.StringWidth(text)
+		fitted = append(fitted, Span{Text: text, Style: span.Style})
+	}
+
+	return fitted
+}
+
+// Strings returns the lines with styles applied.
+func Strings(lines []Line) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = line.String()
+	}
+
+	return out
+}
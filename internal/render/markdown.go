@@ -0,0 +1,476 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// MinMarkdownWidth is the narrowest width Markdown is rendered at; narrower text is shown as is.
+const MinMarkdownWidth = 20
+
+// MarkdownStyles holds styles for Markdown text.
+type MarkdownStyles struct {
+	Heading     lipgloss.Style
+	Heading1    lipgloss.Style
+	Bold        lipgloss.Style
+	Italic      lipgloss.Style
+	Link        lipgloss.Style
+	InlineCode  lipgloss.Style
+	Bullet      lipgloss.Style
+	Quote       lipgloss.Style
+	Rule        lipgloss.Style
+	Fence       lipgloss.Style // code block frame and language
+	Code        lipgloss.Style
+	Keyword     lipgloss.Style
+	String      lipgloss.Style
+	Number      lipgloss.Style
+	Comment     lipgloss.Style
+	TableBorder lipgloss.Style
+	TableHeader lipgloss.Style
+}
+
+// NewMarkdownStyles creates the default Markdown styles.
+func NewMarkdownStyles() *MarkdownStyles {
+	c := theme.Colors()
+
+	return &MarkdownStyles{
+		Heading: lipgloss.NewStyle().
+			Foreground(c.Info).
+			Bold(true),
+		Heading1: lipgloss.NewStyle().
+			Foreground(c.Accent).
+			Bold(true).
+			Underline(true),
+		Bold: lipgloss.NewStyle().
+			Foreground(c.Strong).
+			Bold(true),
+		Italic: lipgloss.NewStyle().
+			Foreground(c.Text).
+			Italic(true),
+		Link: lipgloss.NewStyle().
+			Foreground(c.Info).
+			Underline(true),
+		InlineCode: lipgloss.NewStyle().
+			Foreground(c.Code),
+		Bullet: lipgloss.NewStyle().
+			Foreground(c.Accent),
+		Quote: lipgloss.NewStyle().
+			Foreground(c.Secondary).
+			Italic(true),
+		Rule: lipgloss.NewStyle().
+			Foreground(c.Muted),
+		Fence: lipgloss.NewStyle().
+			Foreground(c.Muted),
+		Code: lipgloss.NewStyle().
+			Foreground(c.Secondary),
+		Keyword: lipgloss.NewStyle().
+			Foreground(c.Keyword),
+		String: lipgloss.NewStyle().
+			Foreground(c.Success),
+		Number: lipgloss.NewStyle().
+			Foreground(c.Number),
+		Comment: lipgloss.NewStyle().
+			Foreground(c.Subtle).
+			Italic(true),
+		TableBorder: lipgloss.NewStyle().
+			Foreground(c.Muted),
+		TableHeader: lipgloss.NewStyle().
+			Foreground(c.Strong).
+			Bold(true),
+	}
+}
+
+var (
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)             //nolint:gochecknoglobals // package-level config
+	bulletPattern   = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+(.*)$`)           //nolint:gochecknoglobals // package-level config
+	rulePattern     = regexp.MustCompile(`^\s*([-*_])(\s*([-*_]))*\s*$`)                //nolint:gochecknoglobals // package-level config
+	fencePattern    = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+#.-]*)")            //nolint:gochecknoglobals // package-level config
+	tableSepPattern = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`) //nolint:gochecknoglobals // package-level config
+)
+
+// Markdown renders Markdown text as lines no wider than width.
+// It returns nil when width is below MinMarkdownWidth; the caller should show the text as is.
+func Markdown(text string, width int, styles *MarkdownStyles, base lipgloss.Style) []Line {
+	if width < MinMarkdownWidth {
+		return nil
+	}
+
+	md := markdown{width: width, styles: styles, base: base, glyphs: theme.Symbols()}
+	src := strings.Split(strings.ReplaceAll(ExpandTabs(text), "\r", ""), "\n")
+	for i := 0; i < len(src); i++ {
+		line := src[i]
+
+		if m := fencePattern.FindStringSubmatch(line); m != nil {
+			i = md.codeBlock(src, i, m[1], m[2])
+
+			continue
+		}
+		if strings.Contains(line, "|") && i+1 < len(src) && tableSepPattern.MatchString(src[i+1]) && strings.Contains(src[i+1], "-") {
+			i = md.table(src, i)
+
+			continue
+		}
+
+		md.line(line)
+	}
+
+	// Drop trailing blank lines.
+	for len(md.lines) > 0 && len(md.lines[len(md.lines)-1]) == 0 {
+		md.lines = md.lines[:len(md.lines)-1]
+	}
+
+	return md.lines
+}
+
+// markdown holds the state of a Markdown rendering.
+type markdown struct {
+	width  int
+	styles *MarkdownStyles
+	base   lipgloss.Style // style of plain text
+	glyphs theme.Glyphs
+	lines  []Line
+}
+
+// line renders a single line outside code blocks and tables.
+func (md *markdown) line(line string) {
+	trimmed := strings.TrimSpace(line)
+
+	switch {
+	case trimmed == "":
+		md.lines = append(md.lines, Line{})
+
+	case headingPattern.MatchString(trimmed):
+		m := headingPattern.FindStringSubmatch(trimmed)
+		style := md.styles.Heading
+		if len(m[1]) == 1 {
+			style = md.styles.Heading1
+		}
+		md.lines = append(md.lines, wrapSpans(restyle(md.inline(m[2]), style), md.width)...)
+
+	case rulePattern.MatchString(trimmed) && len(strings.Map(dropSpaces, trimmed)) >= 3:
+		md.lines = append(md.lines, NewLine(strings.Repeat(md.glyphs.HLine, md.width), md.styles.Rule))
+
+	case bulletPattern.MatchString(line):
+		m := bulletPattern.FindStringSubmatch(line)
+		indent := min(runewidth.StringWidth(m[1]), md.width/2)
+		marker := m[2]
+		if marker == "-" || marker == "*" || marker == "+" {
+			marker = md.glyphs.Bullet
+		}
+		marker += " "
+		prefix := Line{{Text: strings.Repeat(" ", indent)}, {Text: marker, Style: md.styles.Bullet}}
+		md.prefixed(prefix, indent+runewidth.StringWidth(marker), md.inline(m[3]))
+
+	case strings.HasPrefix(trimmed, ">"):
+		quote := strings.TrimSpace(strings.TrimLeft(trimmed, ">"))
+		prefix := NewLine(md.glyphs.VLine+" ", md.styles.Quote)
+		md.prefixed(prefix, 2, restyle(md.inline(quote), md.styles.Quote))
+
+	default:
+		md.lines = append(md.lines, wrapSpans(md.inline(line), md.width)...)
+	}
+}
+
+// prefixed wraps spans after a prefix; continuation lines are indented by indent columns.
+func (md *markdown) prefixed(prefix Line, indent int, spans []Span) {
+	for i, line := range wrapSpans(spans, max(1, md.width-indent)) {
+		if i == 0 {
+			md.lines = append(md.lines, append(append(Line{}, prefix...), line...))
+
+			continue
+		}
+		md.lines = append(md.lines, Indent(indent, line))
+	}
+}
+
+// codeBlock renders a fenced code block starting at src[start] and returns the index of its last line.
+// Long code lines are truncated rather than wrapped.
+func (md *markdown) codeBlock(src []string, start int, fence, lang string) int {
+	header := md.glyphs.TopLeft + md.glyphs.HLine
+	if lang != "" {
+		header += " " + lang
+	}
+	md.lines = append(md.lines, NewLine(Fit(header, md.width), md.styles.Fence))
+
+	end := len(src) - 1
+	for i := start + 1; i < len(src); i++ {
+		if strings.HasPrefix(strings.TrimSpace(src[i]), fence[:3]) {
+			end = i
+
+			break
+		}
+		code := highlight(src[i], lang, md.styles)
+		md.lines = append(md.lines, append(NewLine(md.glyphs.VLine+" ", md.styles.Fence), code...).Fit(md.width))
+		end = i
+	}
+	md.lines = append(md.lines, NewLine(md.glyphs.BottomLeft+md.glyphs.HLine, md.styles.Fence))
+
+	return end
+}
+
+// table renders a table starting at src[start] and returns the index of its last line.
+// Columns are shrunk to fit the width; a table that cannot fit is shown as wrapped text.
+func (md *markdown) table(src []string, start int) int {
+	end := start + 1
+	rows := [][]string{md.cells(src[start])}
+	for i := start + 2; i < len(src) && strings.Contains(src[i], "|"); i++ {
+		rows = append(rows, md.cells(src[i]))
+		end = i
+	}
+
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	widths := make([]int, cols)
+	for _, row := range rows {
+		for c, cell := range row {
+			widths[c] = max(widths[c], runewidth.StringWidth(cell), 1)
+		}
+	}
+
+	if !fitColumns(widths, md.width-3*(cols-1)) {
+		for i := start; i <= end; i++ {
+			md.lines = append(md.lines, wrapSpans(md.inline(src[i]), md.width)...)
+		}
+
+		return end
+	}
+
+	border := md.styles.TableBorder
+	for r, row := range rows {
+		var line Line
+		for c, width := range widths {
+			if c > 0 {
+				line = append(line, Span{Text: " " + md.glyphs.VLine + " ", Style: border})
+			}
+			cell := ""
+			if c < len(row) {
+				cell = row[c]
+			}
+			if runewidth.StringWidth(cell) > width {
+				cell = runewidth.Truncate(cell, width, md.glyphs.Ellipsis)
+			}
+			style := md.base
+			if r == 0 {
+				style = md.styles.TableHeader
+			}
+			line = append(line, Span{Text: runewidth.FillRight(cell, width), Style: style})
+		}
+		md.lines = append(md.lines, line)
+
+		if r == 0 {
+			parts := make([]string, len(widths))
+			for c, width := range widths {
+				parts[c] = strings.Repeat(md.glyphs.HLine, width)
+			}
+			md.lines = append(md.lines, NewLine(strings.Join(parts, md.glyphs.HLine+md.glyphs.Cross+md.glyphs.HLine), border))
+		}
+	}
+
+	return end
+}
+
+// cells splits a table row into cell texts with inline markup removed.
+func (md *markdown) cells(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	row = strings.TrimSuffix(row, "|")
+	row = strings.ReplaceAll(row, `\|`, "\x00")
+
+	parts := strings.Split(row, "|")
+	cells := make([]string, len(parts))
+	for i, part := range parts {
+		text := strings.ReplaceAll(strings.TrimSpace(part), "\x00", "|")
+		cells[i] = Line(md.inline(text)).Plain()
+	}
+
+	return cells
+}
+
+// fitColumns shrinks the widest columns until their total fits in available.
+// It returns false when the columns cannot fit even at their minimum width.
+func fitColumns(widths []int, available int) bool {
+	const minColumn = 3
+
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	for total > available {
+		widest := 0
+		for c, w := range widths {
+			if w > widths[widest] {
+				widest = c
+			}
+		}
+		if widths[widest] <= minColumn {
+			return false
+		}
+		widths[widest]--
+		total--
+	}
+
+	return true
+}
+
+// inline parses emphasis, code spans and links into styled spans.
+func (md *markdown) inline(text string) []Span {
+	var spans []Span
+	var plain strings.Builder
+	emit := func(s string, style lipgloss.Style) {
+		if plain.Len() > 0 {
+			spans = append(spans, Span{Text: plain.String(), Style: md.base})
+			plain.Reset()
+		}
+		spans = append(spans, Span{Text: s, Style: style})
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				emit(rest[1:1+end], md.styles.InlineCode)
+				i += end + 2
+
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				emit(rest[2:2+end], md.styles.Bold)
+				i += end + 4
+
+				continue
+			}
+		case (rest[0] == '*' || rest[0] == '_') && len(rest) > 1 && rest[1] != ' ' && (i == 0 || !isWordByte(text[i-1])):
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 && (2+end >= len(rest) || !isWordByte(rest[2+end])) {
+				emit(rest[1:1+end], md.styles.Italic)
+				i += end + 2
+
+				continue
+			}
+		case rest[0] == '[':
+			if mid := strings.Index(rest, "]("); mid > 0 {
+				if end := strings.IndexByte(rest[mid:], ')'); end > 0 {
+					emit(rest[1:mid], md.styles.Link)
+					i += mid + end + 1
+
+					continue
+				}
+			}
+		}
+		plain.WriteByte(text[i])
+		i++
+	}
+	if plain.Len() > 0 {
+		spans = append(spans, Span{Text: plain.String(), Style: md.base})
+	}
+
+	return spans
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func dropSpaces(r rune) rune {
+	if unicode.IsSpace(r) {
+		return -1
+	}
+
+	return r
+}
+
+// restyle applies style to the spans that have the base style, keeping inline code and links.
+func restyle(spans []Span, style lipgloss.Style) []Span {
+	out := make([]Span, len(spans))
+	for i, span := range spans {
+		out[i] = Span{Text: span.Text, Style: style.Inherit(span.Style)}
+	}
+
+	return out
+}
+
+// wrapSpans wraps styled spans to width, breaking at spaces where possible.
+func wrapSpans(spans []Span, width int) []Line {
+	width = max(1, width)
+	var lines []Line
+	var current Line
+	currentWidth := 0
+
+	flush := func() {
+		// Drop trailing spaces.
+		for len(current) > 0 && strings.TrimSpace(current[len(current)-1].Text) == "" {
+			currentWidth -= runewidth.StringWidth(current[len(current)-1].Text)
+			current = current[:len(current)-1]
+		}
+		lines = append(lines, current)
+		current = nil
+		currentWidth = 0
+	}
+
+	for _, span := range spans {
+		for _, word := range splitWords(span.Text) {
+			w := runewidth.StringWidth(word)
+			if strings.TrimSpace(word) == "" {
+				if currentWidth == 0 {
+					continue
+				}
+				if currentWidth+w > width {
+					flush()
+
+					continue
+				}
+			} else if currentWidth > 0 && currentWidth+w > width {
+				flush()
+			}
+
+			// Break words longer than a line.
+			for w > width {
+				head := Fit(word, width-currentWidth)
+				if head == "" && currentWidth > 0 {
+					flush()
+
+					continue
+				}
+				if head == "" {
+					// A single rune wider than the line; take it anyway to make progress.
+					_, size := utf8.DecodeRuneInString(word)
+					head = word[:size]
+				}
+				current = append(current, Span{Text: head, Style: span.Style})
+				flush()
+				word = word[len(head):]
+				w = runewidth.StringWidth(word)
+			}
+			current = append(current, Span{Text: word, Style: span.Style})
+			currentWidth += w
+		}
+	}
+	if len(current) > 0 || len(lines) == 0 {
+		flush()
+	}
+
+	return lines
+}
+
+// splitWords splits text into alternating runs of spaces and non-spaces.
+func splitWords(text string) []string {
+	var words []string
+	start := 0
+	for i := 1; i <= len(text); i++ {
+		if i == len(text) || (text[i] == ' ') != (text[i-1] == ' ') {
+			words = append(words, text[start:i])
+			start = i
+		}
+	}
+
+	return words
+}
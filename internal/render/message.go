@@ -0,0 +1,352 @@
+package render
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/sters/cc-session-tailing/internal/filter"
+	"github.com/sters/cc-session-tailing/internal/parser"
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// Styles holds styles for message content.
+type Styles struct {
+	Think    lipgloss.Style
+	Text     lipgloss.Style
+	Tool     lipgloss.Style
+	User     lipgloss.Style
+	Label    lipgloss.Style
+	Cursor   lipgloss.Style // label of the block under the cursor
+	Time     lipgloss.Style // timestamps and durations
+	Marker   lipgloss.Style // idle gap and turn markers
+	Tools    *ToolStyles
+	Markdown *MarkdownStyles
+}
+
+// NewStyles creates the default message styles.
+func NewStyles() *Styles {
+	c := theme.Colors()
+
+	return &Styles{
+		Think: lipgloss.NewStyle().
+			Foreground(c.Subtle).
+			Italic(true),
+		Text: lipgloss.NewStyle().
+			Foreground(c.Text),
+		Tool: lipgloss.NewStyle().
+			Foreground(c.Tool).
+			Bold(true),
+		User: lipgloss.NewStyle().
+			Foreground(c.User).
+			Bold(true),
+		Label: lipgloss.NewStyle().
+			Foreground(c.Muted),
+		Cursor: lipgloss.NewStyle().
+			Foreground(c.OnAccent).
+			Background(c.Accent).
+			Bold(true),
+		Time: lipgloss.NewStyle().
+			Foreground(c.Subtle),
+		Marker: lipgloss.NewStyle().
+			Foreground(c.Muted).
+			Italic(true),
+		Tools:    NewToolStyles(),
+		Markdown: NewMarkdownStyles(),
+	}
+}
+
+// BlockState is the display state of a content block.
+type BlockState struct {
+	Expanded bool // show the full content instead of a one-line summary
+	Selected bool // block is under the cursor
+}
+
+// Options controls how a session is rendered.
+type Options struct {
+	Width        int            // line width; no rendered line is wider
+	Filter       *filter.Filter // blocks to hide; nil shows everything
+	MaxDiffLines int            // diff lines shown for a collapsed file edit (0 = no limit)
+	Markdown     bool           // render assistant text as Markdown
+	Timestamps   TimestampMode  // message times, idle gaps and durations
+	Now          time.Time      // reference time for relative timestamps
+	IdleGap      time.Duration  // pause marked between messages; 0 = DefaultIdleGap
+	// State returns the display state of the block at index block of message msg.
+	// nil renders all blocks collapsed.
+	State func(msg, block int, b parser.ContentBlock) BlockState
+	// Cache keeps what was rendered of the session between calls; nil renders every message.
+	// Messages whose block state changed must be marked with Cache.Invalidate.
+	Cache *Cache
+}
+
+// BlockRange is the line range of a rendered content block.
+type BlockRange struct {
+	Msg   int // message index
+	Block int // block index within the message
+	Start int // first line
+	End   int // last line + 1
+}
+
+// Document is a rendered session.
+type Document struct {
+	Lines     []Line
+	MsgStarts []int        // first line of each message
+	Blocks    []BlockRange // rendered blocks in display order
+}
+
+// Renderer renders session messages into lines.
+type Renderer struct {
+	styles *Styles
+}
+
+// NewRenderer creates a renderer. nil styles use the defaults.
+func NewRenderer(styles *Styles) *Renderer {
+	if styles == nil {
+		styles = NewStyles()
+	}
+
+	return &Renderer{styles: styles}
+}
+
+// Styles returns the styles used by the renderer.
+func (r *Renderer) Styles() *Styles {
+	return r.styles
+}
+
+// Session renders messages from oldest to newest.
+// With opts.Cache, only the messages added since the last render are taken in, and only the
+// messages that are new or changed are rendered; the others are reused.
+func (r *Renderer) Session(messages []parser.Message, opts Options) Document {
+	cache := opts.Cache
+	if cache == nil {
+		cache = NewCache()
+	}
+	cache.begin(r.styles, messages, opts)
+	if opts.Timestamps == TimestampsRelative {
+		cache.age(opts.Now)
+	}
+
+	// Times take a column on the left of the message content.
+	blockOpts := opts
+	if opts.Timestamps != TimestampsOff {
+		blockOpts.Width = max(1, opts.Width-timeGutterWidth)
+	}
+
+	from := cache.first()
+	for i := from; i < len(messages); i++ {
+		if !cache.rendered(i) {
+			cache.put(i, r.message(i, messages[i], &cache.state, opts, blockOpts))
+		}
+	}
+
+	return cache.assemble(from)
+}
+
+// message renders one message: the idle gap marker before it, its shown blocks and the turn end marker after it.
+func (r *Renderer) message(i int, msg parser.Message, state *running, opts, blockOpts Options) entry {
+	var e entry
+	timed := opts.Timestamps != TimestampsOff
+	gutter := "" // time label, shown on the first line only
+
+	if at := msg.Time(); timed && !at.IsZero() {
+		if gap := r.gapMarker(state.prevs[i], at, opts); gap != nil {
+			e.lines = append(e.lines, gap)
+		}
+		gutter = timeLabel(at, opts)
+		if opts.Timestamps == TimestampsRelative {
+			e.expires = ageChanges(at, opts.Now)
+		}
+	}
+
+	for j, block := range msg.Message.Content {
+		if !opts.Filter.Allows(filter.KindOf(msg.Type, block), state.toolNames.Name(block)) {
+			continue
+		}
+		var blockState BlockState
+		if opts.State != nil {
+			blockState = opts.State(i, j, block)
+		}
+		call := ToolCall{Result: state.results[block.ID]}
+		if timed {
+			call.Elapsed = state.toolElapsed[block.ID]
+		}
+		lines := r.Block(block, msg.Type, call, blockState, blockOpts)
+		if len(lines) == 0 {
+			continue
+		}
+		if timed {
+			// The time is shown on the first line of the message only.
+			for k, line := range lines {
+				lines[k] = append(Line{{Text: runewidth.FillRight(gutter, timeGutterWidth), Style: r.styles.Time}}, line...)
+				gutter = ""
+			}
+		}
+		start := len(e.lines)
+		e.ranges = append(e.ranges, BlockRange{Msg: i, Block: j, Start: start, End: start + len(lines)})
+		e.lines = append(e.lines, lines...)
+	}
+
+	if d, ok := state.turnEnds[i]; ok && timed {
+		marker := NewLine(theme.Symbols().End+" turn "+FormatDuration(d), r.styles.Marker)
+		e.lines = append(e.lines, Indent(timeGutterWidth, marker).Fit(opts.Width))
+	}
+
+	return e
+}
+
+// gapMarker returns a marker line when the pause between two messages is at least the idle gap, or nil.
+// The date is added when the day changes.
+func (r *Renderer) gapMarker(prev, next time.Time, opts Options) Line {
+	idle := opts.IdleGap
+	if idle <= 0 {
+		idle = DefaultIdleGap
+	}
+	if prev.IsZero() || next.Sub(prev) < idle {
+		return nil
+	}
+
+	g := theme.Symbols()
+	text := strings.Repeat(g.HLine, 2) + " idle " + FormatDuration(next.Sub(prev))
+	if prev.Local().Format(time.DateOnly) != next.Local().Format(time.DateOnly) {
+		text += " " + g.Separator + " " + next.Local().Format("Mon Jan 2")
+	}
+	text += " "
+	if fill := opts.Width - runewidth.StringWidth(text); fill > 0 {
+		text += strings.Repeat(g.HLine, fill)
+	}
+
+	return NewLine(Fit(text, opts.Width), r.styles.Marker)
+}
+
+// Block renders a content block of a message of type msgType.
+// call describes the outcome of a tool_use block.
+func (r *Renderer) Block(block parser.ContentBlock, msgType string, call ToolCall, state BlockState, opts Options) []Line {
+	lines := r.block(block, msgType, call, state, opts)
+	for i, line := range lines {
+		lines[i] = line.Fit(opts.Width)
+	}
+
+	return lines
+}
+
+func (r *Renderer) block(block parser.ContentBlock, msgType string, call ToolCall, state BlockState, opts Options) []Line {
+	// Tool results arrive as user messages and are rendered below.
+	if msgType == "user" && block.Type != "tool_result" {
+		if block.Type != "text" || block.Text == "" {
+			return nil
+		}
+
+		return r.labeled("[USER] ", Wrap(block.Text, contentWidth("[USER] ", opts.Width)), r.styles.User, state)
+	}
+
+	switch block.Type {
+	case "thinking":
+		text := block.Thinking
+		if text == "" {
+			text = block.Text
+		}
+
+		return r.collapsible("[THINK] ", text, r.styles.Think, state, opts)
+
+	case "text":
+		if block.Text == "" {
+			return nil
+		}
+		if opts.Markdown {
+			// Narrow views fall back to plain text.
+			md := Markdown(block.Text, contentWidth("[TEXT] ", opts.Width), r.styles.Markdown, r.styles.Text)
+			if md != nil {
+				return r.labeledLines("[TEXT] ", md, state)
+			}
+		}
+
+		return r.labeled("[TEXT] ", Wrap(block.Text, contentWidth("[TEXT] ", opts.Width)), r.styles.Text, state)
+
+	case "tool_use":
+		width := contentWidth("[TOOL] ", opts.Width)
+		lines := r.labeled("[TOOL] ", []string{Truncate(block.Name, width)}, r.styles.Tool, state)
+		if opts.Timestamps != TimestampsOff && call.Elapsed > 0 {
+			lines[0] = append(lines[0], Span{Text: "  " + FormatDuration(call.Elapsed), Style: r.styles.Time})
+		}
+
+		// Show tool input with the view registered for the tool.
+		ctx := ToolContext{
+			Width:        width,
+			Expanded:     state.Expanded,
+			MaxDiffLines: opts.MaxDiffLines,
+			Result:       call.Result,
+			Styles:       r.styles.Tools,
+		}
+		for _, line := range ToolInput(block, ctx) {
+			lines = append(lines, Indent(runewidth.StringWidth("[TOOL] "), line))
+		}
+
+		return lines
+
+	case "tool_result":
+		return r.collapsible("[RESULT] ", block.ResultText(), r.styles.Text, state, opts)
+	}
+
+	return nil
+}
+
+// collapsible renders text wrapped when expanded, otherwise as a single truncated line.
+func (r *Renderer) collapsible(label, text string, style lipgloss.Style, state BlockState, opts Options) []Line {
+	if text == "" {
+		return nil
+	}
+
+	width := contentWidth(label, opts.Width)
+	if state.Expanded {
+		return r.labeled(label, Wrap(ExpandTabs(text), width), style, state)
+	}
+
+	return r.labeled(label, []string{Truncate(text, width)}, style, state)
+}
+
+// labeled renders content lines after a label; continuation lines are indented to the label width.
+func (r *Renderer) labeled(label string, content []string, style lipgloss.Style, state BlockState) []Line {
+	lines := make([]Line, len(content))
+	for i, text := range content {
+		lines[i] = NewLine(text, style)
+	}
+
+	return r.labeledLines(label, lines, state)
+}
+
+// labeledLines renders styled content lines after a label.
+func (r *Renderer) labeledLines(label string, content []Line, state BlockState) []Line {
+	labelSpan := Span{Text: label, Style: r.styles.Label}
+	if state.Selected {
+		// Highlight the label text only, not the space after it.
+		text := label[:len(label)-1]
+		if theme.Plain() {
+			// "[TEXT]" becomes "> TEXT" so the cursor does not rely on color.
+			text = "> " + strings.Trim(text, "[]")
+		}
+		labelSpan = Span{Text: text, Style: r.styles.Cursor}
+	}
+	labelWidth := runewidth.StringWidth(label)
+
+	lines := make([]Line, 0, len(content))
+	for i, content := range content {
+		if i == 0 {
+			line := Line{labelSpan}
+			if state.Selected {
+				line = append(line, Span{Text: " "})
+			}
+			lines = append(lines, append(line, content...))
+
+			continue
+		}
+		lines = append(lines, Indent(labelWidth, content))
+	}
+
+	return lines
+}
+
+// contentWidth returns the width left for content after a label.
+func contentWidth(label string, width int) int {
+	return max(1, width-runewidth.StringWidth(label))
+}
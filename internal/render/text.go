@@ -0,0 +1,102 @@
+// Package render turns session messages into display lines.
+package render
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Truncate flattens text to a single line and truncates it to maxWidth with a "..." suffix.
+func Truncate(text string, maxWidth int) string {
+	maxWidth = max(4, maxWidth)
+
+	text = strings.ReplaceAll(text, "\n", " ")
+	text = strings.ReplaceAll(text, "\r", "")
+	text = strings.ReplaceAll(text, "\t", " ")
+
+	if runewidth.StringWidth(text) <= maxWidth {
+		return text
+	}
+
+	return runewidth.Truncate(text, maxWidth, "...")
+}
+
+// Fit truncates a single line to width without a suffix.
+func Fit(text string, width int) string {
+	if runewidth.StringWidth(text) > width {
+		return runewidth.Truncate(text, max(0, width), "")
+	}
+
+	return text
+}
+
+// ExpandTabs replaces tabs with spaces so that wrapped lines keep their width.
+func ExpandTabs(text string) string {
+	return strings.ReplaceAll(text, "\t", "    ")
+}
+
+// Wrap wraps text to width, keeping existing line breaks.
+// Lines are broken at spaces where possible; no returned line is wider than width.
+func Wrap(text string, width int) []string {
+	if width <= 0 {
+		return []string{text}
+	}
+
+	var lines []string
+	text = strings.ReplaceAll(text, "\r", "")
+
+	for _, para := range strings.Split(text, "\n") {
+		if para == "" {
+			lines = append(lines, "")
+
+			continue
+		}
+
+		for runewidth.StringWidth(para) > width {
+			breakAt := findBreakPoint(para, width)
+			if breakAt <= 0 {
+				// A single rune wider than the line; take it anyway to make progress.
+				_, breakAt = utf8.DecodeRuneInString(para)
+			}
+			lines = append(lines, Fit(para[:breakAt], width))
+			para = strings.TrimLeft(para[breakAt:], " ")
+		}
+		if para != "" {
+			lines = append(lines, para)
+		}
+	}
+
+	return lines
+}
+
+// findBreakPoint returns the byte position to break text at so that the first part fits in width.
+// The last space that fits is preferred; otherwise the text is broken after the last rune that fits.
+func findBreakPoint(text string, width int) int {
+	currentWidth := 0
+	fits := 0 // bytes that fit in width
+	lastSpace := -1
+
+	for i, r := range text {
+		rw := runewidth.RuneWidth(r)
+		if currentWidth+rw > width {
+			break
+		}
+		if r == ' ' {
+			lastSpace = i
+		}
+		currentWidth += rw
+		fits = i + utf8.RuneLen(r)
+	}
+
+	// A space right after the fitting part is also a clean break.
+	if fits < len(text) && text[fits] == ' ' {
+		return fits
+	}
+	if lastSpace > 0 {
+		return lastSpace
+	}
+
+	return fits
+}
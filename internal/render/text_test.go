@@ -0,0 +1,65 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{name: "fits", text: "hello world", width: 20, want: []string{"hello world"}},
+		{name: "breaks at the last space", text: "hello big world", width: 10, want: []string{"hello big", "world"}},
+		{name: "space right after the fitting part", text: "hello world", width: 5, want: []string{"hello", "world"}},
+		{name: "long word", text: "abcdefghij", width: 4, want: []string{"abcd", "efgh", "ij"}},
+		{name: "keeps line breaks", text: "a\n\nb\r\n", width: 10, want: []string{"a", "", "b", ""}},
+		{name: "zero width", text: "hello world", width: 0, want: []string{"hello world"}},
+		{name: "negative width", text: "hello", width: -3, want: []string{"hello"}},
+		{name: "width of one", text: "abc", width: 1, want: []string{"a", "b", "c"}},
+		{name: "wide runes", text: "日本語です", width: 4, want: []string{"日本", "語で", "す"}},
+		{name: "wide rune at an odd width", text: "日本語", width: 3, want: []string{"日", "本", "語"}},
+		{name: "rune wider than the line is left out rather than overflow", text: "日本", width: 1, want: []string{"", ""}},
+		{name: "leading space", text: " abcdef", width: 3, want: []string{" ab", "cde", "f"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Wrap(tt.text, tt.width)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("Wrap(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+			for _, line := range got {
+				if tt.width > 0 && runewidth.StringWidth(line) > tt.width {
+					t.Errorf("line %q is wider than %d", line, tt.width)
+				}
+			}
+		})
+	}
+}
+
+func TestFindBreakPoint(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  int
+	}{
+		{text: "hello world", width: 8, want: 5},
+		{text: "hello world", width: 5, want: 5},
+		{text: "abcdef", width: 3, want: 3},
+		{text: "日本語", width: 3, want: 3},
+		{text: "日本語", width: 1, want: 0},
+		{text: "abc", width: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := findBreakPoint(tt.text, tt.width); got != tt.want {
+			t.Errorf("findBreakPoint(%q, %d) = %d, want %d", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package render
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sters/cc-session-tailing/internal/filter"
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+// DefaultIdleGap is the pause between messages that is marked when Options.IdleGap is not set.
+const DefaultIdleGap = 5 * time.Minute
+
+// timeGutterWidth is the width of the timestamp column, including the space after it.
+const timeGutterWidth = 9
+
+// TimestampMode selects how message times are shown.
+type TimestampMode int
+
+const (
+	// TimestampsOff hides times, idle gaps and durations.
+	TimestampsOff TimestampMode = iota
+	// TimestampsAbsolute shows the time of day of each message.
+	TimestampsAbsolute
+	// TimestampsRelative shows how long ago each message was written.
+	TimestampsRelative
+)
+
+// Next returns the mode that follows m when cycling off -> absolute -> relative.
+func (m TimestampMode) Next() TimestampMode {
+	return (m + 1) % 3
+}
+
+// String returns the mode name.
+func (m TimestampMode) String() string {
+	switch m {
+	case TimestampsAbsolute:
+		return "absolute"
+	case TimestampsRelative:
+		return "relative"
+	default:
+		return "off"
+	}
+}
+
+// ParseTimestampMode parses a mode name; unknown names are off.
+func ParseTimestampMode(s string) TimestampMode {
+	switch s {
+	case "absolute":
+		return TimestampsAbsolute
+	case "relative":
+		return TimestampsRelative
+	default:
+		return TimestampsOff
+	}
+}
+
+// ToolCall holds what is known about the outcome of a tool_use block.
+type ToolCall struct {
+	Result  *parser.ToolUseResult // structured result, if recorded
+	Elapsed time.Duration         // time from the call to its result; 0 = unknown or pending
+}
+
+// FormatDuration formats a duration compactly, e.g. "4.2s", "3m05s", "2h10m".
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d < 10*time.Second:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%02dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
+// formatAge formats how long ago something happened, e.g. "12s ago", "3h ago".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < 5*time.Second:
+		return "now"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours())/24)
+	}
+}
+
+// ageChanges returns when the age of something that happened at t, as formatted by formatAge at now, changes next.
+func ageChanges(t, now time.Time) time.Time {
+	d := now.Sub(t)
+	step := 24 * time.Hour
+	switch {
+	case d < 5*time.Second:
+		return t.Add(5 * time.Second)
+	case d < time.Minute:
+		step = time.Second
+	case d < time.Hour:
+		step = time.Minute
+	case d < 24*time.Hour:
+		step = time.Hour
+	}
+
+	return t.Add((d/step + 1) * step)
+}
+
+// timeLabel returns the timestamp column text of a message, right-aligned to the column.
+func timeLabel(t time.Time, opts Options) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	var label string
+	if opts.Timestamps == TimestampsRelative {
+		label = formatAge(opts.Now.Sub(t))
+	} else {
+		label = t.Local().Format("15:04:05")
+	}
+
+	return fmt.Sprintf("%*s", timeGutterWidth-1, label)
+}
+
+// isPrompt returns whether a message is a user prompt rather than a tool result.
+func isPrompt(msg parser.Message) bool {
+	if msg.Type != "user" {
+		return false
+	}
+	for _, block := range msg.Message.Content {
+		if block.Type == "text" && block.Text != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// running holds what is known of a session from the messages added so far:
+// tool names and results, tool call durations and turn ends.
+// A turn runs from a user prompt to the assistant message that stops with "end_turn";
+// without one, the turn ends at the last assistant message before the next prompt.
+type running struct {
+	messages      int                              // messages added
+	tail          messageID                        // the last message added
+	toolNames     filter.ToolNames                 // tool_use ID -> tool name
+	results       map[string]*parser.ToolUseResult // tool_use ID -> structured result
+	toolElapsed   map[string]time.Duration         // tool_use ID -> time until its result
+	turnEnds      map[int]time.Duration            // message index -> duration of the turn it ends
+	prevs         []time.Time                      // message index -> time of the previous timestamped message
+	calls         map[string]toolUse               // tool_use ID -> the call
+	turnStart     time.Time                        // time of the prompt that started the current turn
+	lastAssistant int                              // last assistant message of the current turn; -1 = none
+	lastAt        time.Time                        // time of that message
+	last          time.Time                        // time of the last timestamped message
+}
+
+// messageID tells messages apart well enough to notice that a session was replaced.
+type messageID struct {
+	timestamp string
+	msgType   string
+}
+
+// toolUse is a tool call: the message it was made in and when.
+type toolUse struct {
+	msg int
+	at  time.Time
+}
+
+// newRunning creates the state of a session without messages.
+func newRunning() running {
+	return running{
+		toolNames:     make(filter.ToolNames),
+		results:       make(map[string]*parser.ToolUseResult),
+		toolElapsed:   make(map[string]time.Duration),
+		turnEnds:      make(map[int]time.Duration),
+		calls:         make(map[string]toolUse),
+		lastAssistant: -1,
+	}
+}
+
+// continues returns whether messages are the messages added so far followed by new ones.
+func (s *running) continues(messages []parser.Message) bool {
+	if len(messages) < s.messages {
+		return false
+	}
+	if s.messages == 0 {
+		return true
+	}
+	msg := messages[s.messages-1]
+
+	return messageID{timestamp: msg.Timestamp, msgType: msg.Type} == s.tail
+}
+
+// add adds the next message of the session and returns the earlier messages whose rendering it changes:
+// the ones with the tool calls it answers and the one whose turn it ends.
+func (s *running) add(msg parser.Message) []int {
+	i := s.messages
+	s.messages++
+	s.tail = messageID{timestamp: msg.Timestamp, msgType: msg.Type}
+	s.toolNames.Observe(msg)
+
+	at := msg.Time()
+	s.prevs = append(s.prevs, s.last)
+	if !at.IsZero() {
+		s.last = at
+	}
+
+	var changed []int
+	for _, block := range msg.Message.Content {
+		switch block.Type {
+		case "tool_use":
+			s.calls[block.ID] = toolUse{msg: i, at: at}
+		case "tool_result":
+			if msg.ToolUseResult != nil && block.ToolUseID != "" {
+				s.results[block.ToolUseID] = msg.ToolUseResult
+			}
+			call, ok := s.calls[block.ToolUseID]
+			if !ok {
+				continue
+			}
+			if !call.at.IsZero() && !at.IsZero() {
+				s.toolElapsed[block.ToolUseID] = at.Sub(call.at)
+			}
+			changed = append(changed, call.msg)
+		}
+	}
+
+	switch {
+	case isPrompt(msg):
+		if end := s.endTurn(); end >= 0 {
+			changed = append(changed, end)
+		}
+		s.turnStart = at
+	case msg.Type == "assistant":
+		s.lastAssistant = i
+		s.lastAt = at
+		if msg.Message.StopReason == "end_turn" {
+			s.endTurn()
+		}
+	}
+
+	return changed
+}
+
+// endTurn records the duration of the current turn and returns the message that ends it, or -1.
+func (s *running) endTurn() int {
+	end := -1
+	if !s.turnStart.IsZero() && s.lastAssistant >= 0 && !s.lastAt.IsZero() {
+		end = s.lastAssistant
+		s.turnEnds[end] = s.lastAt.Sub(s.turnStart)
+	}
+	s.turnStart = time.Time{}
+	s.lastAssistant = -1
+
+	return end
+}
@@ -0,0 +1,320 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/sters/cc-session-tailing/internal/diff"
+	"github.com/sters/cc-session-tailing/internal/parser"
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// ToolStyles holds styles for tool call views.
+type ToolStyles struct {
+	Input    lipgloss.Style // generic input text
+	Key      lipgloss.Style // field names
+	Emphasis lipgloss.Style // the main argument: command, path, pattern, URL
+	Dim      lipgloss.Style // secondary details
+	Diff     *DiffStyles
+}
+
+// NewToolStyles creates the default tool view styles.
+func NewToolStyles() *ToolStyles {
+	c := theme.Colors()
+
+	return &ToolStyles{
+		Input: lipgloss.NewStyle().
+			Foreground(c.Secondary),
+		Key: lipgloss.NewStyle().
+			Foreground(c.Subtle),
+		Emphasis: lipgloss.NewStyle().
+			Foreground(c.Text).
+			Bold(true),
+		Dim: lipgloss.NewStyle().
+			Foreground(c.Subtle).
+			Italic(true),
+		Diff: NewDiffStyles(),
+	}
+}
+
+// ToolContext holds what a tool view needs besides the tool_use block.
+type ToolContext struct {
+	Width        int                   // available width; no returned line is wider
+	Expanded     bool                  // show full content instead of a summary
+	MaxDiffLines int                   // diff lines shown when not expanded (0 = no limit)
+	Result       *parser.ToolUseResult // structured result of the call, if recorded
+	Styles       *ToolStyles
+}
+
+// ToolRenderer renders the input of a tool call as lines no wider than ctx.Width.
+type ToolRenderer func(block parser.ContentBlock, ctx ToolContext) []Line
+
+// toolRenderers holds tool views by exact tool name.
+var toolRenderers = map[string]ToolRenderer{ //nolint:gochecknoglobals // package-level config
+	"Bash":      renderBash,
+	"Read":      renderRead,
+	"Grep":      renderGrep,
+	"WebFetch":  renderWebFetch,
+	"Edit":      renderFileEdit,
+	"MultiEdit": renderFileEdit,
+	"Write":     renderFileEdit,
+}
+
+// toolPrefixRenderers holds tool views by tool name prefix, checked in order.
+var toolPrefixRenderers = []struct { //nolint:gochecknoglobals // package-level config
+	prefix string
+	render ToolRenderer
+}{
+	{prefix: "mcp__", render: renderMCP},
+}
+
+// RegisterTool sets the view for a tool name, replacing any existing one.
+func RegisterTool(name string, r ToolRenderer) {
+	toolRenderers[name] = r
+}
+
+// RegisterToolPrefix sets the view for all tools whose names start with prefix.
+// Exact names registered with RegisterTool take precedence.
+func RegisterToolPrefix(prefix string, r ToolRenderer) {
+	toolPrefixRenderers = append(toolPrefixRenderers, struct {
+		prefix string
+		render ToolRenderer
+	}{prefix: prefix, render: r})
+}
+
+// lookupTool returns the view for a tool, falling back to the generic key/value view.
+func lookupTool(name string) ToolRenderer {
+	if r, ok := toolRenderers[name]; ok {
+		return r
+	}
+	for _, p := range toolPrefixRenderers {
+		if strings.HasPrefix(name, p.prefix) {
+			return p.render
+		}
+	}
+
+	return renderGenericTool
+}
+
+// ToolInput renders the input of a tool_use block with the view registered for its tool.
+func ToolInput(block parser.ContentBlock, ctx ToolContext) []Line {
+	if ctx.Styles == nil {
+		ctx.Styles = NewToolStyles()
+	}
+	ctx.Width = max(1, ctx.Width)
+
+	return lookupTool(block.Name)(block, ctx)
+}
+
+func inputMap(block parser.ContentBlock) map[string]any {
+	m, _ := block.Input.(map[string]any)
+
+	return m
+}
+
+func stringField(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+
+	return s
+}
+
+// intField returns a numeric field; JSON numbers are decoded as float64.
+func intField(m map[string]any, key string) (int, bool) {
+	f, ok := m[key].(float64)
+
+	return int(f), ok
+}
+
+// text renders a possibly multi-line value: wrapped when expanded, otherwise a single truncated line.
+// prefix is shown before the first line; continuation lines are indented to match it.
+func text(value, prefix string, style lipgloss.Style, ctx ToolContext) []Line {
+	prefixWidth := runewidth.StringWidth(prefix)
+	width := max(1, ctx.Width-prefixWidth)
+	if !ctx.Expanded {
+		return []Line{NewLine(prefix+Truncate(value, width), style)}
+	}
+
+	wrapped := Wrap(ExpandTabs(value), width)
+	lines := make([]Line, 0, len(wrapped))
+	indent := strings.Repeat(" ", prefixWidth)
+	for i, line := range wrapped {
+		if i == 0 {
+			lines = append(lines, NewLine(prefix+line, style))
+		} else {
+			lines = append(lines, NewLine(indent+line, style))
+		}
+	}
+
+	return lines
+}
+
+// renderBash shows the command and its description.
+func renderBash(block parser.ContentBlock, ctx ToolContext) []Line {
+	input := inputMap(block)
+	lines := text(stringField(input, "command"), "$ ", ctx.Styles.Emphasis, ctx)
+
+	desc := stringField(input, "description")
+	if background, _ := input["run_in_background"].(bool); background {
+		desc = strings.TrimSpace(desc + " (background)")
+	}
+	if desc != "" {
+		lines = append(lines, text(desc, "# ", ctx.Styles.Dim, ctx)...)
+	}
+
+	return lines
+}
+
+// renderRead shows the path and the line range.
+func renderRead(block parser.ContentBlock, ctx ToolContext) []Line {
+	input := inputMap(block)
+	lines := text(stringField(input, "file_path"), "", ctx.Styles.Emphasis, ctx)
+
+	offset, hasOffset := intField(input, "offset")
+	limit, hasLimit := intField(input, "limit")
+	var lineRange string
+	switch {
+	case hasOffset && hasLimit:
+		lineRange = fmt.Sprintf("lines %d-%d", offset, offset+limit-1)
+	case hasOffset:
+		lineRange = fmt.Sprintf("from line %d", offset)
+	case hasLimit:
+		lineRange = fmt.Sprintf("lines 1-%d", limit)
+	}
+	if pages := stringField(input, "pages"); pages != "" {
+		lineRange = strings.TrimSpace(lineRange + " pages " + pages)
+	}
+	if lineRange != "" {
+		lines = append(lines, NewLine(Fit(lineRange, ctx.Width), ctx.Styles.Dim))
+	}
+
+	return lines
+}
+
+// grepOptions are the Grep options shown after the pattern, in display order.
+var grepOptions = []string{"glob", "type", "output_mode", "-i", "-n", "-A", "-B", "-C", "multiline", "head_limit"} //nolint:gochecknoglobals // package-level config
+
+// renderGrep shows the pattern, the path and the options.
+func renderGrep(block parser.ContentBlock, ctx ToolContext) []Line {
+	input := inputMap(block)
+	lines := text("/"+stringField(input, "pattern")+"/", "", ctx.Styles.Emphasis, ctx)
+	if path := stringField(input, "path"); path != "" {
+		lines = append(lines, text(path, "in ", ctx.Styles.Input, ctx)...)
+	}
+
+	var options []string
+	for _, key := range grepOptions {
+		if value, ok := input[key]; ok {
+			options = append(options, fmt.Sprintf("%s=%v", key, value))
+		}
+	}
+	if len(options) > 0 {
+		lines = append(lines, text(strings.Join(options, " "), "", ctx.Styles.Dim, ctx)...)
+	}
+
+	return lines
+}
+
+// renderWebFetch shows the URL and the prompt.
+func renderWebFetch(block parser.ContentBlock, ctx ToolContext) []Line {
+	input := inputMap(block)
+	lines := text(stringField(input, "url"), "", ctx.Styles.Emphasis, ctx)
+	if prompt := stringField(input, "prompt"); prompt != "" {
+		lines = append(lines, text(prompt, "", ctx.Styles.Dim, ctx)...)
+	}
+
+	return lines
+}
+
+// renderMCP shows the MCP server and tool, followed by the input fields.
+// MCP tool names have the form mcp__<server>__<tool>.
+func renderMCP(block parser.ContentBlock, ctx ToolContext) []Line {
+	server, tool, _ := strings.Cut(strings.TrimPrefix(block.Name, "mcp__"), "__")
+	header := Line{{Text: "server: ", Style: ctx.Styles.Key}, {Text: server, Style: ctx.Styles.Emphasis}}
+	if tool != "" {
+		header = append(header, Span{Text: "  tool: ", Style: ctx.Styles.Key}, Span{Text: tool, Style: ctx.Styles.Emphasis})
+	}
+	if header.Width() > ctx.Width {
+		header = NewLine(Fit(server+"/"+tool, ctx.Width), ctx.Styles.Emphasis)
+	}
+
+	return append([]Line{header}, renderGenericTool(block, ctx)...)
+}
+
+// renderFileEdit shows a diff of the edited file.
+func renderFileEdit(block parser.ContentBlock, ctx ToolContext) []Line {
+	maxLines := ctx.MaxDiffLines
+	if ctx.Expanded {
+		maxLines = 0
+	}
+	path, hunks := diff.ForToolUse(block, ctx.Result)
+
+	return Diff(path, diff.Lines(hunks), ctx.Width, maxLines, ctx.Styles.Diff)
+}
+
+// renderGenericTool shows the input as sorted "key: value" lines.
+// Values are flattened to one truncated line unless expanded; expanded multi-line
+// or long values are shown below their key, indented.
+func renderGenericTool(block parser.ContentBlock, ctx ToolContext) []Line {
+	if block.Input == nil {
+		return nil
+	}
+
+	input, ok := block.Input.(map[string]any)
+	if !ok {
+		return text(fmt.Sprintf("%v", block.Input), "", ctx.Styles.Input, ctx)
+	}
+
+	keys := make([]string, 0, len(input))
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]Line, 0, len(keys))
+	for _, key := range keys {
+		value := formatValue(input[key], ctx.Expanded)
+		if !ctx.Expanded {
+			value = strings.ReplaceAll(value, "\n", "\\n")
+			lines = append(lines, NewLine(Truncate(key+": "+value, ctx.Width), ctx.Styles.Input))
+
+			continue
+		}
+
+		line := key + ": " + value
+		if !strings.Contains(value, "\n") && runewidth.StringWidth(line) <= ctx.Width {
+			lines = append(lines, NewLine(line, ctx.Styles.Input))
+
+			continue
+		}
+		lines = append(lines, NewLine(Fit(key+":", ctx.Width), ctx.Styles.Input))
+		for _, valueLine := range Wrap(value, max(1, ctx.Width-2)) {
+			lines = append(lines, NewLine("  "+valueLine, ctx.Styles.Input))
+		}
+	}
+
+	return lines
+}
+
+// formatValue formats an input value; non-strings are shown as JSON, indented when expanded.
+func formatValue(value any, expanded bool) string {
+	if s, ok := value.(string); ok {
+		return ExpandTabs(strings.ReplaceAll(s, "\r", ""))
+	}
+
+	var jsonBytes []byte
+	var err error
+	if expanded {
+		jsonBytes, err = json.MarshalIndent(value, "", "  ")
+	} else {
+		jsonBytes, err = json.Marshal(value)
+	}
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(jsonBytes)
+}
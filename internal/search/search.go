@@ -0,0 +1,291 @@
+// Package search finds messages across sessions and session files.
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+const (
+	// snippetContext is the number of bytes of context shown before a match.
+	snippetContext = 30
+	// snippetLength is the maximum number of bytes of a snippet.
+	snippetLength = 300
+)
+
+// Query describes what to search for.
+type Query struct {
+	Pattern string
+	Regex   bool // treat Pattern as a regular expression instead of a literal
+}
+
+// Matcher finds a compiled query in text.
+// Matching is case-insensitive unless the pattern contains an upper-case letter.
+// In a regular expression only literal characters count: escapes and classes such as \S or [A-Z] do not.
+type Matcher struct {
+	re *regexp.Regexp
+}
+
+// Compile compiles a query into a matcher.
+func Compile(q Query) (*Matcher, error) {
+	pattern := q.Pattern
+	if !q.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !caseSensitive(q) {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	return &Matcher{re: re}, nil
+}
+
+// caseSensitive returns whether the literal characters of a query include an upper-case letter.
+func caseSensitive(q Query) bool {
+	if !q.Regex {
+		return strings.IndexFunc(q.Pattern, unicode.IsUpper) >= 0
+	}
+
+	return hasUpperLiteral(q.Pattern)
+}
+
+// hasUpperLiteral returns whether a regular expression holds an upper-case letter outside of
+// escapes, character classes and group flags or names. The pattern is scanned as written rather
+// than parsed, because parsing merges alternatives such as (a|B) into a class.
+func hasUpperLiteral(pattern string) bool {
+	for i := 0; i < len(pattern); {
+		rest := pattern[i:]
+		switch {
+		case strings.HasPrefix(rest, `\Q`):
+			// Quoted text is literal up to \E.
+			quoted, _, _ := strings.Cut(rest[2:], `\E`)
+			if strings.IndexFunc(quoted, unicode.IsUpper) >= 0 {
+				return true
+			}
+			i += 2 + len(quoted) + 2
+		case rest[0] == '\\':
+			i += escapeLen(rest)
+		case rest[0] == '[':
+			i += classLen(rest)
+		case strings.HasPrefix(rest, "(?"):
+			// Flags such as (?i) or (?s:, or a name such as (?P<Name>.
+			end := strings.IndexAny(rest, "):>")
+			if end < 0 {
+				return false
+			}
+			i += end + 1
+		default:
+			r, size := utf8.DecodeRuneInString(rest)
+			if unicode.IsUpper(r) {
+				return true
+			}
+			i += size
+		}
+	}
+
+	return false
+}
+
+// escapeLen returns the length of the escape sequence at the start of s, such as \S, \pL, \p{Greek} or \x{41}.
+func escapeLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case 'p', 'P', 'x':
+		if strings.HasPrefix(s[2:], "{") {
+			if end := strings.IndexByte(s, '}'); end >= 0 {
+				return end + 1
+			}
+
+			return len(s)
+		}
+		if s[1] == 'x' {
+			return min(len(s), 4)
+		}
+
+		return min(len(s), 3)
+	}
+	_, size := utf8.DecodeRuneInString(s[1:])
+
+	return 1 + size
+}
+
+// classLen returns the length of the character class at the start of s, such as [A-Z], [^]x] or [[:upper:]].
+func classLen(s string) int {
+	i := 1
+	if strings.HasPrefix(s[i:], "^") {
+		i++
+	}
+	if strings.HasPrefix(s[i:], "]") {
+		// A leading ] is a member of the class.
+		i++
+	}
+	for i < len(s) {
+		switch {
+		case s[i] == ']':
+			return i + 1
+		case s[i] == '\\':
+			i += escapeLen(s[i:])
+		case strings.HasPrefix(s[i:], "[:"):
+			end := strings.Index(s[i:], ":]")
+			if end < 0 {
+				return len(s)
+			}
+			i += end + 2
+		default:
+			i++
+		}
+	}
+
+	return len(s)
+}
+
+// Find returns the byte range of the first match in text.
+func (m *Matcher) Find(text string) (int, int, bool) {
+	loc := m.re.FindStringIndex(text)
+	if loc == nil {
+		return 0, 0, false
+	}
+
+	return loc[0], loc[1], true
+}
+
+// FindAll returns the byte ranges of all matches in text, in order.
+func (m *Matcher) FindAll(text string) [][]int {
+	return m.re.FindAllStringIndex(text, -1)
+}
+
+// Target is a session to search.
+type Target struct {
+	SessionID string
+	Messages  []parser.Message
+}
+
+// Result is a single matching message.
+type Result struct {
+	SessionID    string
+	Path         string // session file; set for archived results
+	Archived     bool   // found in a file not held by the session manager
+	MessageIndex int
+	Timestamp    time.Time
+	Snippet      string // single-line text around the match
+	CutBefore    bool   // text before the snippet was left out
+	CutAfter     bool   // text after the snippet was left out
+}
+
+// Sessions searches the messages of each target.
+// At most limit results are returned (0 = no limit).
+func Sessions(targets []Target, m *Matcher, limit int) []Result {
+	var results []Result
+	for _, target := range targets {
+		for i, msg := range target.Messages {
+			r, ok := match(msg, m)
+			if !ok {
+				continue
+			}
+			r.SessionID = target.SessionID
+			r.MessageIndex = i
+			results = append(results, r)
+			if limit > 0 && len(results) >= limit {
+				return results
+			}
+		}
+	}
+
+	return results
+}
+
+// Files searches JSONL session files under root, skipping files for which skip returns true.
+// Session IDs of results are the file paths relative to root without the extension.
+// At most limit results are returned (0 = no limit).
+func Files(root string, skip func(path string) bool, m *Matcher, limit int) ([]Result, error) {
+	var results []Result
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil || info == nil {
+			// Skip inaccessible paths.
+			return filepath.SkipDir
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".jsonl") || skip(path) {
+			return nil
+		}
+
+		messages, err := parser.ParseFile(path)
+		if err != nil {
+			return nil //nolint:nilerr // unreadable files are skipped
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		sessionID := strings.TrimSuffix(filepath.ToSlash(rel), ".jsonl")
+
+		for i, msg := range messages {
+			r, ok := match(msg, m)
+			if !ok {
+				continue
+			}
+			r.SessionID = sessionID
+			r.Path = path
+			r.Archived = true
+			r.MessageIndex = i
+			results = append(results, r)
+			if limit > 0 && len(results) >= limit {
+				return filepath.SkipAll
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return results, fmt.Errorf("failed to search files in %s: %w", root, err)
+	}
+
+	return results, nil
+}
+
+// match returns a result with the time of the message and a snippet around its first match.
+func match(msg parser.Message, m *Matcher) (Result, bool) {
+	text := msg.PlainText()
+	start, _, ok := m.Find(text)
+	if !ok {
+		return Result{}, false
+	}
+
+	from := max(0, start-snippetContext)
+	// Do not cut a multi-byte character in half.
+	for from > 0 && !isRuneStart(text[from]) {
+		from--
+	}
+
+	to := min(len(text), from+snippetLength)
+	for to < len(text) && !isRuneStart(text[to]) {
+		to++
+	}
+
+	return Result{
+		Timestamp: msg.Time(),
+		Snippet:   strings.Join(strings.Fields(text[from:to]), " "),
+		CutBefore: from > 0,
+		CutAfter:  to < len(text),
+	}, true
+}
+
+// isRuneStart reports whether b is the first byte of a UTF-8 sequence.
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
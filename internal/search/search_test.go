@@ -0,0 +1,141 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   Query
+		text    string
+		want    []string // matched text, in order
+		wantErr bool
+	}{
+		{name: "lower-case literal ignores case", query: Query{Pattern: "go"}, text: "Go go GO", want: []string{"Go", "go", "GO"}},
+		{name: "upper-case literal is case-sensitive", query: Query{Pattern: "Go"}, text: "Go go GO", want: []string{"Go"}},
+		{name: "literal metacharacters", query: Query{Pattern: "a.b("}, text: "axb( a.b(", want: []string{"a.b("}},
+		{name: "regex", query: Query{Pattern: `err\w+`, Regex: true}, text: "ERRORS errno", want: []string{"ERRORS", "errno"}},
+		{name: "upper-case regex literal", query: Query{Pattern: `Err\w*`, Regex: true}, text: "Errno err", want: []string{"Errno"}},
+		{name: "escape is not a literal", query: Query{Pattern: `\S+x`, Regex: true}, text: "aX bx", want: []string{"aX", "bx"}},
+		{name: "class is not a literal", query: Query{Pattern: `[A-Z]x`, Regex: true}, text: "Ax bX", want: []string{"Ax", "bX"}},
+		{name: "upper-case literal in a group", query: Query{Pattern: `(a|B)c`, Regex: true}, text: "ac Ac Bc bc", want: []string{"ac", "Bc"}},
+		{name: "upper-case alternative", query: Query{Pattern: `x(a|B|C)`, Regex: true}, text: "xa xA xB xb", want: []string{"xa", "xB"}},
+		{name: "posix class", query: Query{Pattern: `[[:upper:]]x`, Regex: true}, text: "Ax aX", want: []string{"Ax", "aX"}},
+		{name: "unicode class", query: Query{Pattern: `\p{Lu}x`, Regex: true}, text: "Ax aX", want: []string{"Ax", "aX"}},
+		{name: "hex escape", query: Query{Pattern: `\x{41}x`, Regex: true}, text: "Ax aX", want: []string{"Ax", "aX"}},
+		{name: "group name", query: Query{Pattern: `(?P<Word>ab)`, Regex: true}, text: "ab AB", want: []string{"ab", "AB"}},
+		{name: "quoted literal", query: Query{Pattern: `\QA.\E`, Regex: true}, text: "A. a.", want: []string{"A."}},
+		{name: "folded non-ASCII literal", query: Query{Pattern: "ⱥ"}, text: "Ⱥ ⱥ", want: []string{"Ⱥ", "ⱥ"}},
+		{name: "invalid regex", query: Query{Pattern: "(", Regex: true}, wantErr: true},
+		{name: "parenthesis as a literal", query: Query{Pattern: "("}, text: "f(x)", want: []string{"("}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var got []string
+			for _, loc := range m.FindAll(tt.text) {
+				got = append(got, tt.text[loc[0]:loc[1]])
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("matches = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// textMessage returns a user message holding text.
+func textMessage(text string) parser.Message {
+	return parser.Message{
+		Type:    "user",
+		Message: parser.MessageContent{Content: []parser.ContentBlock{{Type: "text", Text: text}}},
+	}
+}
+
+func TestSessions(t *testing.T) {
+	long := strings.Repeat("é", 40) + " needle\n\nafter " + strings.Repeat("x", 400)
+	targets := []Target{
+		{SessionID: "a", Messages: []parser.Message{textMessage("hay"), textMessage("a needle  in\nhay")}},
+		{SessionID: "b", Messages: []parser.Message{textMessage(long), textMessage("needle")}},
+	}
+	m, err := Compile(Query{Pattern: "needle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := Sessions(targets, m, 0)
+	if len(results) != 3 {
+		t.Fatalf("results = %+v, want 3", results)
+	}
+
+	first := results[0]
+	if first.SessionID != "a" || first.MessageIndex != 1 || first.Snippet != "a needle in hay" || first.CutBefore || first.CutAfter {
+		t.Errorf("first result = %+v", first)
+	}
+
+	cut := results[1]
+	if !cut.CutBefore || !cut.CutAfter || !strings.Contains(cut.Snippet, "needle after") {
+		t.Errorf("result in a long message = %+v", cut)
+	}
+	if !strings.HasPrefix(cut.Snippet, "é") || len(cut.Snippet) > snippetLength+1 {
+		t.Errorf("snippet %q is not cut at characters within %d bytes", cut.Snippet, snippetLength)
+	}
+
+	if limited := Sessions(targets, m, 2); len(limited) != 2 {
+		t.Errorf("limited results = %d, want 2", len(limited))
+	}
+}
+
+func TestFiles(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) string {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+	line := `{"type":"user","message":{"role":"user","content":"find the needle"}}` + "\n"
+	write("one.jsonl", line)
+	skipped := write("two.jsonl", line)
+	nested := write("one/subagents/agent-1.jsonl", `{"type":"user","message":{"role":"user","content":"hay"}}`+"\n"+line)
+	write("notes.txt", "needle")
+
+	m, err := Compile(Query{Pattern: "needle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := Files(root, func(path string) bool { return path == skipped }, m, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]Result)
+	for _, r := range results {
+		got[r.SessionID] = r
+	}
+	if len(got) != 2 {
+		t.Fatalf("results = %+v, want one.jsonl and the subagent file", results)
+	}
+	if r := got["one/subagents/agent-1"]; r.Path != nested || !r.Archived || r.MessageIndex != 1 {
+		t.Errorf("nested result = %+v", r)
+	}
+}
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"path"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+// titleWidth is the maximum display width of a title taken from a user prompt.
+const titleWidth = 80
+
+// Info describes a session for display and lookup.
+type Info struct {
+	Title        string // summary of the session, or its first user prompt
+	CWD          string // working directory
+	GitBranch    string // git branch checked out in the working directory
+	SubagentType string // for subagents, the subagent_type of the Task call that started it
+}
+
+// Info returns descriptive metadata of a session, taken from its messages.
+// Later messages win, so a branch switch during the session shows the new branch.
+func (m *Manager) Info(sessionID string) Info {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.sessions[sessionID]
+	if !ok {
+		return Info{}
+	}
+
+	return m.info(s)
+}
+
+// info returns the metadata of a session. The caller holds m.mu.
+func (m *Manager) info(s *Session) Info {
+	info := Info{
+		Title:     s.title(),
+		CWD:       s.stats.cwd,
+		GitBranch: s.stats.branch,
+	}
+
+	if s.IsSubagent {
+		if parent, ok := m.sessions[s.ParentID]; ok {
+			info.SubagentType = subagentType(parent.Messages, strings.TrimPrefix(path.Base(s.ID), "agent-"))
+		}
+	}
+
+	return info
+}
+
+// title returns the summary of the session, or its first user prompt.
+func (s *Session) title() string {
+	if s.stats.summary != "" {
+		return s.stats.summary
+	}
+
+	return runewidth.Truncate(s.stats.prompt, titleWidth, "...")
+}
+
+// stats holds values folded from the messages of a session as they arrive,
+// so that titles and sort keys do not walk every message again.
+type stats struct {
+	summary string          // last session summary
+	cwd     string          // last working directory
+	branch  string          // last git branch
+	prompt  string          // first user prompt
+	tokens  int             // tokens used by assistant replies
+	replies map[string]bool // IDs of the assistant replies counted in tokens
+}
+
+// add folds new messages into the stats. Later messages win, except for the first prompt.
+func (st *stats) add(messages []parser.Message) {
+	for _, msg := range messages {
+		if msg.Summary != "" {
+			st.summary = msg.Summary
+		}
+		if msg.CWD != "" {
+			st.cwd = msg.CWD
+		}
+		if msg.GitBranch != "" {
+			st.branch = msg.GitBranch
+		}
+		if st.prompt == "" && msg.Type == "user" {
+			st.prompt = promptText(msg)
+		}
+		st.addUsage(msg)
+	}
+}
+
+// addUsage counts the tokens of an assistant reply.
+// A reply split over several messages reports its usage on each, so it is counted once per message ID.
+func (st *stats) addUsage(msg parser.Message) {
+	usage := msg.Message.Usage
+	if msg.Type != "assistant" || usage == nil {
+		return
+	}
+	if id := msg.Message.ID; id != "" {
+		if st.replies[id] {
+			return
+		}
+		if st.replies == nil {
+			st.replies = make(map[string]bool)
+		}
+		st.replies[id] = true
+	}
+	st.tokens += usage.Total()
+}
+
+// promptText returns the first line of the text a user typed, or "" for tool results.
+func promptText(msg parser.Message) string {
+	for _, block := range msg.Message.Content {
+		if block.Type != "text" {
+			continue
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(block.Text), "\n")
+		if line != "" {
+			return line
+		}
+	}
+
+	return ""
+}
+
+// subagentType returns the subagent_type input of the Task call whose result started the given agent.
+func subagentType(messages []parser.Message, agentID string) string {
+	callID := ""
+	for _, msg := range messages {
+		if msg.ToolUseResult == nil || msg.ToolUseResult.AgentID != agentID {
+			continue
+		}
+		for _, block := range msg.Message.Content {
+			if block.Type == "tool_result" {
+				callID = block.ToolUseID
+			}
+		}
+	}
+	if callID == "" {
+		return ""
+	}
+
+	for _, msg := range messages {
+		for _, block := range msg.Message.Content {
+			if block.Type != "tool_use" || block.ID != callID {
+				continue
+			}
+			if input, ok := block.Input.(map[string]any); ok {
+				if t, ok := input["subagent_type"].(string); ok {
+					return t
+				}
+			}
+		}
+	}
+
+	return ""
+}
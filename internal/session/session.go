@@ -22,6 +22,8 @@ type Session struct {
 	Messages   []parser.Message
 	Offset     int64
 	LastUpdate time.Time
+
+	stats stats // folded from Messages as the manager adds them
 }
 
 // Node represents a session with its children for tree display.
@@ -40,6 +42,8 @@ type Manager struct {
 	excludePatterns []string        // patterns to exclude from display
 	recentlyUpdated map[string]bool // tracks recently updated session IDs
 	sessionOrder    []string        // maintains insertion order of session IDs
+	readCursors     map[string]int  // sessionID -> number of messages the user has read
+	pinned          map[int]string  // display slot -> pinned sessionID (skipped by LRU eviction)
 }
 
 // NewManager creates a new session manager.
@@ -51,6 +55,8 @@ func NewManager(panels int) *Manager {
 		excludePatterns: defaultExcludePatterns,
 		recentlyUpdated: make(map[string]bool),
 		sessionOrder:    make([]string, 0),
+		readCursors:     make(map[string]int),
+		pinned:          make(map[int]string),
 	}
 }
 
@@ -133,6 +139,7 @@ func (m *Manager) UpdateSession(sessionID string, messages []parser.Message, new
 	}
 
 	s.Messages = append(s.Messages, messages...)
+	s.stats.add(messages)
 	s.Offset = newOffset
 	s.LastUpdate = time.Now()
 	m.recentlyUpdated[sessionID] = true
@@ -146,10 +153,8 @@ func (m *Manager) assignPanel(sessionID string) {
 	}
 
 	// Check if already assigned
-	for _, sid := range m.panelAssign {
-		if sid == sessionID {
-			return
-		}
+	if m.isAssigned(sessionID) {
+		return
 	}
 
 	// Find an empty panel.
@@ -169,11 +174,16 @@ func (m *Manager) assignPanel(sessionID string) {
 }
 
 // getOldestPanel returns the panel with the oldest session.
+// Panels holding pinned sessions are never chosen.
 func (m *Manager) getOldestPanel() int {
 	oldestPanel := -1
 	var oldestTime time.Time
 
 	for panel, sessionID := range m.panelAssign {
+		if m.isPinned(sessionID) {
+			continue
+		}
+
 		s, ok := m.sessions[sessionID]
 		if !ok {
 			return panel // Empty session, use this panel
@@ -188,15 +198,42 @@ func (m *Manager) getOldestPanel() int {
 	return oldestPanel
 }
 
-// GetPanelSessions returns sessions for each panel, sorted by LastUpdate (newest first).
+// isPinned reports whether a session is pinned to a display slot.
+func (m *Manager) isPinned(sessionID string) bool {
+	for _, sid := range m.pinned {
+		if sid == sessionID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetPanelSessions returns sessions for each panel.
+// Pinned sessions stay in their slot; the other slots are filled by LastUpdate (newest first).
 func (m *Manager) GetPanelSessions() []*Session {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// Collect all assigned sessions.
+	return m.panelSessions()
+}
+
+func (m *Manager) panelSessions() []*Session {
+	result := make([]*Session, m.panels)
+
+	// Place pinned sessions first.
+	placed := make(map[string]bool)
+	for slot, sessionID := range m.pinned {
+		if s, ok := m.sessions[sessionID]; ok && slot < m.panels {
+			result[slot] = s
+			placed[sessionID] = true
+		}
+	}
+
+	// Collect the remaining assigned sessions.
 	var assigned []*Session
 	for _, sessionID := range m.panelAssign {
-		if s, ok := m.sessions[sessionID]; ok {
+		if s, ok := m.sessions[sessionID]; ok && !placed[sessionID] {
 			assigned = append(assigned, s)
 		}
 	}
@@ -210,17 +247,128 @@ func (m *Manager) GetPanelSessions() []*Session {
 		}
 	}
 
-	// Fill result with sorted sessions, padding with nil if needed.
-	result := make([]*Session, m.panels)
+	// Fill the free slots with sorted sessions, padding with nil if needed.
+	next := 0
 	for i := range m.panels {
-		if i < len(assigned) {
-			result[i] = assigned[i]
+		if result[i] == nil && next < len(assigned) {
+			result[i] = assigned[next]
+			next++
 		}
 	}
 
 	return result
 }
 
+// TogglePin pins the session shown in a display slot to that slot, or unpins it.
+// Returns whether the slot is pinned afterwards.
+func (m *Manager) TogglePin(slot int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.pinned[slot]; ok {
+		delete(m.pinned, slot)
+
+		return false
+	}
+
+	sessions := m.panelSessions()
+	if slot < 0 || slot >= len(sessions) || sessions[slot] == nil {
+		return false
+	}
+	m.pinned[slot] = sessions[slot].ID
+
+	return true
+}
+
+// PinToSlot shows a session in a display slot and pins it there.
+// A pin of the session to another slot is moved.
+func (m *Manager) PinToSlot(slot int, sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if slot < 0 || slot >= m.panels {
+		return
+	}
+	if _, ok := m.sessions[sessionID]; !ok {
+		return
+	}
+
+	sessions := m.panelSessions()
+	for s, sid := range m.pinned {
+		if sid == sessionID {
+			delete(m.pinned, s)
+		}
+	}
+	m.pinned[slot] = sessionID
+
+	if !m.isAssigned(sessionID) {
+		// Evict the session shown in the slot, so that it does not keep a panel while hidden.
+		m.replaceAssignment(sessions[slot], sessionID)
+	}
+}
+
+// isAssigned reports whether a session is assigned to a panel.
+func (m *Manager) isAssigned(sessionID string) bool {
+	for _, sid := range m.panelAssign {
+		if sid == sessionID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replaceAssignment assigns the panel of old to a session, or a free panel if old is nil.
+func (m *Manager) replaceAssignment(old *Session, sessionID string) {
+	if old != nil {
+		for panel, sid := range m.panelAssign {
+			if sid == old.ID {
+				m.panelAssign[panel] = sessionID
+
+				return
+			}
+		}
+	}
+	for i := range m.panels {
+		if _, ok := m.panelAssign[i]; !ok {
+			m.panelAssign[i] = sessionID
+
+			return
+		}
+	}
+}
+
+// IsPinnedSlot reports whether a display slot is pinned.
+func (m *Manager) IsPinnedSlot(slot int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.pinned[slot]
+
+	return ok
+}
+
+// SwapPanels exchanges the sessions shown in two display slots.
+// Both sessions are pinned to their new slots so the arrangement sticks.
+func (m *Manager) SwapPanels(a, b int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a == b || a < 0 || b < 0 || a >= m.panels || b >= m.panels {
+		return
+	}
+
+	sessions := m.panelSessions()
+	delete(m.pinned, a)
+	delete(m.pinned, b)
+	if sessions[a] != nil {
+		m.pinned[b] = sessions[a].ID
+	}
+	if sessions[b] != nil {
+		m.pinned[a] = sessions[b].ID
+	}
+}
+
 // GetSession returns a session by ID.
 func (m *Manager) GetSession(sessionID string) *Session {
 	m.mu.RLock()
@@ -234,21 +382,24 @@ func (m *Manager) PanelCount() int {
 	return m.panels
 }
 
-// SetPanelCount sets the number of panels (1-5, cycles).
+// SetPanelCount sets the number of panels (at least 1).
 // When increasing panel count, unassigned sessions are automatically added.
 func (m *Manager) SetPanelCount(count int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if count < 1 {
-		count = 1
-	} else if count > 5 {
-		count = 1
-	}
+	count = max(1, count)
 
 	oldCount := m.panels
 	m.panels = count
 
+	// Drop pins for slots that no longer exist.
+	for slot := range m.pinned {
+		if slot >= count {
+			delete(m.pinned, slot)
+		}
+	}
+
 	// If panel count increased, assign unassigned sessions to new panels.
 	if count > oldCount {
 		m.fillEmptyPanels()
@@ -471,3 +622,87 @@ func (m *Manager) GetRecentlyUpdated() map[string]bool {
 
 	return result
 }
+
+// MarkRead advances the read cursor of a session.
+// count is the number of messages read (the last viewed message index + 1).
+// The cursor never moves backwards.
+func (m *Manager) MarkRead(sessionID string, count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[sessionID]
+	if !ok {
+		return
+	}
+
+	count = min(count, len(s.Messages))
+	if count > m.readCursors[sessionID] {
+		m.readCursors[sessionID] = count
+	}
+}
+
+// MarkAllRead marks every message of every session as read.
+func (m *Manager) MarkAllRead() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, s := range m.sessions {
+		m.readCursors[id] = len(s.Messages)
+	}
+}
+
+// UnreadCount returns the number of messages after the read cursor of a session.
+func (m *Manager) UnreadCount(sessionID string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.sessions[sessionID]
+	if !ok {
+		return 0
+	}
+
+	return max(0, len(s.Messages)-m.readCursors[sessionID])
+}
+
+// UnreadCounts returns the unread message counts of sessions that have unread messages.
+// Excluded sessions are filtered out.
+func (m *Manager) UnreadCounts() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[string]int)
+	for id, s := range m.sessions {
+		if m.shouldExcludeSession(id) {
+			continue
+		}
+		if unread := len(s.Messages) - m.readCursors[id]; unread > 0 {
+			result[id] = unread
+		}
+	}
+
+	return result
+}
+
+// ReadCursors returns a copy of the read cursors.
+func (m *Manager) ReadCursors() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[string]int, len(m.readCursors))
+	for id, count := range m.readCursors {
+		result[id] = count
+	}
+
+	return result
+}
+
+// SetReadCursors replaces the read cursors, e.g. with ones saved by a previous run.
+func (m *Manager) SetReadCursors(cursors map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.readCursors = make(map[string]int, len(cursors))
+	for id, count := range cursors {
+		m.readCursors[id] = count
+	}
+}
@@ -0,0 +1,190 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+)
+
+// withMessages creates a session holding n messages.
+func withMessages(m *Manager, sessionID string, n int) {
+	m.GetOrCreateSession(sessionID, sessionID+".jsonl", false)
+	m.UpdateSession(sessionID, make([]parser.Message, n), 0)
+}
+
+func TestReadCursors(t *testing.T) {
+	m := NewManager(2)
+	withMessages(m, "a", 5)
+	withMessages(m, "b", 3)
+	withMessages(m, "agent-prompt_suggestion-1", 4)
+
+	if got := m.UnreadCount("a"); got != 5 {
+		t.Fatalf("unread of a new session = %d, want 5", got)
+	}
+
+	m.MarkRead("a", 2)
+	m.MarkRead("a", 1)
+	if got := m.UnreadCount("a"); got != 3 {
+		t.Errorf("unread after reading 2 then 1 = %d, want 3 (the cursor never moves back)", got)
+	}
+	m.MarkRead("a", 99)
+	if got := m.ReadCursors()["a"]; got != 5 {
+		t.Errorf("cursor read past the end = %d, want 5", got)
+	}
+	m.MarkRead("missing", 3)
+	if got := m.UnreadCount("missing"); got != 0 {
+		t.Errorf("unread of a missing session = %d, want 0", got)
+	}
+
+	m.UpdateSession("a", make([]parser.Message, 2), 0)
+	counts := m.UnreadCounts()
+	if len(counts) != 2 || counts["a"] != 2 || counts["b"] != 3 {
+		t.Errorf("unread counts = %v, want a:2 b:3 without excluded or read sessions", counts)
+	}
+
+	m.MarkAllRead()
+	if counts := m.UnreadCounts(); len(counts) != 0 {
+		t.Errorf("unread counts after marking all read = %v", counts)
+	}
+}
+
+func TestSetReadCursorsCopiesSavedCursors(t *testing.T) {
+	m := NewManager(1)
+	withMessages(m, "a", 4)
+
+	saved := map[string]int{"a": 3, "gone": 7}
+	m.SetReadCursors(saved)
+	saved["a"] = 0
+	if got := m.UnreadCount("a"); got != 1 {
+		t.Errorf("unread = %d, want 1", got)
+	}
+
+	cursors := m.ReadCursors()
+	cursors["a"] = 0
+	if got := m.UnreadCount("a"); got != 1 {
+		t.Errorf("unread after changing the returned cursors = %d, want 1", got)
+	}
+}
+
+// newManagerAt creates a manager with the given sessions, each updated a minute after the previous one.
+func newManagerAt(panels int, sessionIDs ...string) *Manager {
+	m := NewManager(panels)
+	start := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+	for i, id := range sessionIDs {
+		m.GetOrCreateSession(id, id+".jsonl", false).LastUpdate = start.Add(time.Duration(i) * time.Minute)
+	}
+
+	return m
+}
+
+// shown returns the IDs of the sessions in each display slot, "" for an empty slot.
+func shown(m *Manager) []string {
+	var ids []string
+	for _, s := range m.GetPanelSessions() {
+		id := ""
+		if s != nil {
+			id = s.ID
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func assertShown(t *testing.T, m *Manager, want ...string) {
+	t.Helper()
+
+	got := shown(m)
+	if len(got) != len(want) {
+		t.Fatalf("shown = %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("shown = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestPinToSlotShowsAHiddenSession(t *testing.T) {
+	m := newManagerAt(2, "a", "b", "c") // c took the panel of a
+	assertShown(t, m, "c", "b")
+
+	m.PinToSlot(1, "a")
+	assertShown(t, m, "c", "a")
+	if m.isAssigned("b") {
+		t.Errorf("b keeps a panel while hidden")
+	}
+
+	// Pinning again moves the pin.
+	m.PinToSlot(0, "a")
+	assertShown(t, m, "a", "c")
+	if m.IsPinnedSlot(1) || !m.IsPinnedSlot(0) {
+		t.Errorf("pins = %v, want only slot 0", m.pinned)
+	}
+
+	m.PinToSlot(2, "c")
+	m.PinToSlot(1, "missing")
+	if len(m.pinned) != 1 {
+		t.Errorf("pins = %v after pinning to a missing slot or session", m.pinned)
+	}
+}
+
+func TestEvictionSkipsPinnedSessions(t *testing.T) {
+	m := newManagerAt(2, "a", "b")
+	if !m.TogglePin(1) { // a, the older session
+		t.Fatal("slot 1 not pinned")
+	}
+
+	m.GetOrCreateSession("c", "c.jsonl", false)
+	assertShown(t, m, "c", "a")
+
+	if m.TogglePin(1) {
+		t.Fatal("slot 1 still pinned")
+	}
+	m.GetOrCreateSession("d", "d.jsonl", false)
+	assertShown(t, m, "d", "c")
+}
+
+func TestSwapPanelsPinsBothSessions(t *testing.T) {
+	m := newManagerAt(3, "a", "b", "c")
+	assertShown(t, m, "c", "b", "a")
+
+	m.SwapPanels(0, 2)
+	assertShown(t, m, "a", "b", "c")
+	if !m.IsPinnedSlot(0) || m.IsPinnedSlot(1) || !m.IsPinnedSlot(2) {
+		t.Errorf("pins = %v, want slots 0 and 2", m.pinned)
+	}
+
+	// A newer session takes the free slot, not the swapped ones.
+	m.GetOrCreateSession("d", "d.jsonl", false)
+	assertShown(t, m, "a", "d", "c")
+
+	m.SwapPanels(1, 1)
+	m.SwapPanels(0, 3)
+	assertShown(t, m, "a", "d", "c")
+}
+
+func TestSetPanelCountDropsPinsOfRemovedSlots(t *testing.T) {
+	m := newManagerAt(3, "a", "b", "c")
+	m.TogglePin(0)
+	m.TogglePin(2)
+
+	m.SetPanelCount(2)
+	if !m.IsPinnedSlot(0) || m.IsPinnedSlot(2) {
+		t.Errorf("pins = %v, want slot 0 only", m.pinned)
+	}
+
+	m.SetPanelCount(0)
+	if m.PanelCount() != 1 {
+		t.Errorf("panel count = %d, want at least 1", m.PanelCount())
+	}
+}
+
+func TestSetPanelCountFillsNewPanels(t *testing.T) {
+	m := newManagerAt(1, "a", "b", "c")
+	assertShown(t, m, "c")
+
+	m.SetPanelCount(3)
+	assertShown(t, m, "c", "b", "a")
+}
@@ -0,0 +1,307 @@
+package session
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// idleAfter is how long a session goes without messages before its status is idle.
+const idleAfter = 10 * time.Minute
+
+// SortKey selects the order of sessions in the tree.
+type SortKey int
+
+const (
+	// SortArrival lists sessions newest first in the order they appeared and keeps the display order on refresh.
+	SortArrival SortKey = iota
+	// SortActivity lists the most recently active sessions first.
+	SortActivity
+	// SortCreated lists the most recently started sessions first.
+	SortCreated
+	// SortTitle lists sessions alphabetically by title.
+	SortTitle
+	// SortMessages lists the sessions with the most messages first.
+	SortMessages
+	// SortTokens lists the sessions that used the most tokens first.
+	SortTokens
+	// SortStatus lists working sessions first, then waiting and idle ones, each by activity.
+	SortStatus
+)
+
+// sortKeyNames holds the names of the sort keys, indexed by SortKey.
+var sortKeyNames = []string{"arrival", "activity", "created", "title", "messages", "tokens", "status"} //nolint:gochecknoglobals // package-level config
+
+// SortKeys returns every sort key in cycling order.
+func SortKeys() []SortKey {
+	keys := make([]SortKey, len(sortKeyNames))
+	for i := range keys {
+		keys[i] = SortKey(i)
+	}
+
+	return keys
+}
+
+// Next returns the key that follows k when cycling through SortKeys.
+func (k SortKey) Next() SortKey {
+	return (k + 1) % SortKey(len(sortKeyNames))
+}
+
+// String returns the key name.
+func (k SortKey) String() string {
+	if k < 0 || int(k) >= len(sortKeyNames) {
+		return sortKeyNames[SortArrival]
+	}
+
+	return sortKeyNames[k]
+}
+
+// ParseSortKey parses a key name; unknown names are arrival order.
+func ParseSortKey(s string) SortKey {
+	if i := slices.Index(sortKeyNames, s); i >= 0 {
+		return SortKey(i)
+	}
+
+	return SortArrival
+}
+
+// GroupKey selects the headers the tree groups root sessions under.
+type GroupKey int
+
+const (
+	// GroupNone shows no headers.
+	GroupNone GroupKey = iota
+	// GroupDay groups sessions by the day of their last activity.
+	GroupDay
+	// GroupBranch groups sessions by git branch.
+	GroupBranch
+	// GroupCWD groups sessions by working directory.
+	GroupCWD
+)
+
+// groupKeyNames holds the names of the group keys, indexed by GroupKey.
+var groupKeyNames = []string{"none", "day", "branch", "cwd"} //nolint:gochecknoglobals // package-level config
+
+// GroupKeys returns every group key in cycling order.
+func GroupKeys() []GroupKey {
+	keys := make([]GroupKey, len(groupKeyNames))
+	for i := range keys {
+		keys[i] = GroupKey(i)
+	}
+
+	return keys
+}
+
+// Next returns the key that follows k when cycling through GroupKeys.
+func (k GroupKey) Next() GroupKey {
+	return (k + 1) % GroupKey(len(groupKeyNames))
+}
+
+// String returns the key name.
+func (k GroupKey) String() string {
+	if k < 0 || int(k) >= len(groupKeyNames) {
+		return groupKeyNames[GroupNone]
+	}
+
+	return groupKeyNames[k]
+}
+
+// ParseGroupKey parses a key name; unknown names are no grouping.
+func ParseGroupKey(s string) GroupKey {
+	if i := slices.Index(groupKeyNames, s); i >= 0 {
+		return GroupKey(i)
+	}
+
+	return GroupNone
+}
+
+// Status is what a session is doing, judged from its last messages.
+type Status int
+
+const (
+	// StatusWorking means the assistant is in the middle of a turn.
+	StatusWorking Status = iota
+	// StatusWaiting means the assistant ended its turn and waits for the user.
+	StatusWaiting
+	// StatusIdle means the session has had no messages for a while.
+	StatusIdle
+)
+
+// LastActivity returns the time of the last timestamped message,
+// or the time the session was last read when no message has a timestamp.
+func (s *Session) LastActivity() time.Time {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if t := s.Messages[i].Time(); !t.IsZero() {
+			return t
+		}
+	}
+
+	return s.LastUpdate
+}
+
+// CreatedAt returns the time of the first timestamped message,
+// or the time the session was last read when no message has a timestamp.
+func (s *Session) CreatedAt() time.Time {
+	for _, msg := range s.Messages {
+		if t := msg.Time(); !t.IsZero() {
+			return t
+		}
+	}
+
+	return s.LastUpdate
+}
+
+// Tokens returns the tokens used by the session's assistant replies,
+// counted as the manager adds messages.
+func (s *Session) Tokens() int {
+	return s.stats.tokens
+}
+
+// Status returns what the session is doing at the given time.
+func (s *Session) Status(now time.Time) Status {
+	if now.Sub(s.LastActivity()) >= idleAfter {
+		return StatusIdle
+	}
+
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		switch msg := s.Messages[i]; msg.Type {
+		case "assistant":
+			if msg.Message.StopReason == "end_turn" {
+				return StatusWaiting
+			}
+
+			return StatusWorking
+		case "user":
+			return StatusWorking
+		}
+	}
+
+	return StatusIdle
+}
+
+// GetSessionTreeSortedBy returns sessions as a tree with roots and children sorted by key.
+// Sessions that compare equal stay in arrival order. Excluded sessions are filtered out.
+func (m *Manager) GetSessionTreeSortedBy(key SortKey) []*Node {
+	nodes := m.GetSessionTreePreserveOrder()
+	if key == SortArrival {
+		return nodes
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	compare := m.sessionCompare(key, time.Now())
+	var sortNodes func(nodes []*Node)
+	sortNodes = func(nodes []*Node) {
+		slices.SortStableFunc(nodes, func(a, b *Node) int {
+			return compare(a.Session, b.Session)
+		})
+		for _, node := range nodes {
+			sortNodes(node.Children)
+		}
+	}
+	sortNodes(nodes)
+
+	return nodes
+}
+
+// sessionCompare returns the comparison that orders sessions by key. The caller holds m.mu.
+// Values are computed once per session, since the tree compares each session many times.
+func (m *Manager) sessionCompare(key SortKey, now time.Time) func(a, b *Session) int {
+	switch key {
+	case SortActivity:
+		return compareBy(func(s *Session) int64 { return -s.LastActivity().UnixNano() })
+	case SortCreated:
+		return compareBy(func(s *Session) int64 { return -s.CreatedAt().UnixNano() })
+	case SortTitle:
+		return compareBy(func(s *Session) string { return strings.ToLower(s.title()) })
+	case SortMessages:
+		return compareBy(func(s *Session) int { return -len(s.Messages) })
+	case SortTokens:
+		return compareBy(func(s *Session) int { return -s.Tokens() })
+	case SortStatus:
+		status := compareBy(func(s *Session) Status { return s.Status(now) })
+		activity := compareBy(func(s *Session) int64 { return -s.LastActivity().UnixNano() })
+
+		return func(a, b *Session) int {
+			return cmp.Or(status(a, b), activity(a, b))
+		}
+	default:
+		return func(_, _ *Session) int { return 0 }
+	}
+}
+
+// compareBy returns a comparison of sessions by the value of fn, which is computed once per session.
+func compareBy[T cmp.Ordered](fn func(*Session) T) func(a, b *Session) int {
+	values := make(map[*Session]T)
+	value := func(s *Session) T {
+		v, ok := values[s]
+		if !ok {
+			v = fn(s)
+			values[s] = v
+		}
+
+		return v
+	}
+
+	return func(a, b *Session) int {
+		return cmp.Compare(value(a), value(b))
+	}
+}
+
+// GroupLabels returns the header each root session is grouped under, by session ID.
+// It returns nil for GroupNone.
+func (m *Manager) GroupLabels(key GroupKey, now time.Time) map[string]string {
+	if key == GroupNone {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	labels := make(map[string]string)
+	for _, s := range m.sessions {
+		if s.ParentID != "" || m.shouldExcludeSession(s.ID) {
+			continue
+		}
+		labels[s.ID] = m.groupLabel(s, key, now)
+	}
+
+	return labels
+}
+
+// groupLabel returns the header of one session. The caller holds m.mu.
+func (m *Manager) groupLabel(s *Session, key GroupKey, now time.Time) string {
+	switch key {
+	case GroupDay:
+		return dayLabel(s.LastActivity(), now)
+	case GroupBranch:
+		if branch := s.stats.branch; branch != "" {
+			return branch
+		}
+
+		return "(no branch)"
+	case GroupCWD:
+		if cwd := s.stats.cwd; cwd != "" {
+			return cwd
+		}
+
+		return "(unknown directory)"
+	default:
+		return ""
+	}
+}
+
+// dayLabel names the local day of t: "Today", "Yesterday" or the date.
+func dayLabel(t, now time.Time) string {
+	date := t.Local().Format(time.DateOnly)
+	switch date {
+	case now.Local().Format(time.DateOnly):
+		return "Today"
+	case now.Local().AddDate(0, 0, -1).Format(time.DateOnly):
+		return "Yesterday"
+	default:
+		return t.Local().Format("Mon 2006-01-02")
+	}
+}
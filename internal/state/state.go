@@ -0,0 +1,148 @@
+// Package state persists UI state across restarts.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// appName is the directory name used under the user's state directory.
+const appName = "cc-session-tailing"
+
+// State holds the UI state saved for a single Claude project.
+type State struct {
+	// TreeOrder is the display order of sessions in the tree (flattened, top to bottom).
+	TreeOrder []string `json:"treeOrder,omitempty"`
+	// Collapsed holds the session IDs whose subagents are hidden in the tree.
+	Collapsed []string `json:"collapsed,omitempty"`
+	// TreeSort is the sort order of the tree, e.g. "activity" or "tokens" (empty = arrival order).
+	TreeSort string `json:"treeSort,omitempty"`
+	// TreeGroup is what the tree groups sessions by: "day", "branch", "cwd" or empty for no grouping.
+	TreeGroup string `json:"treeGroup,omitempty"`
+	// Follow is whether the tree selection follows the most active session.
+	Follow bool `json:"follow,omitempty"`
+	// SelectedSession is the session ID selected in the tree.
+	SelectedSession string `json:"selectedSession,omitempty"`
+	// TreeHidden is whether the tree was hidden (fullscreen log).
+	TreeHidden bool `json:"treeHidden,omitempty"`
+	// TreeWidth is the tree pane width set by dragging the divider (0 = automatic).
+	TreeWidth int `json:"treeWidth,omitempty"`
+	// LogScroll is the log viewport offset (-1 = follow bottom).
+	LogScroll int `json:"logScroll"`
+	// PanelCount is the number of panels in panel mode.
+	PanelCount int `json:"panelCount,omitempty"`
+	// Layout is the panel layout name (e.g. "columns", "2x2").
+	Layout string `json:"layout,omitempty"`
+	// PanelScroll is the panel scroll position by session ID (missing = follow bottom).
+	PanelScroll map[string]int `json:"panelScroll,omitempty"`
+	// Markdown is whether assistant text is rendered as Markdown.
+	Markdown bool `json:"markdown,omitempty"`
+	// Timestamps is how message times are shown: "absolute", "relative" or empty for off.
+	Timestamps string `json:"timestamps,omitempty"`
+	// Seen holds the read cursors: session ID -> number of messages the user has seen.
+	Seen map[string]int `json:"seen,omitempty"`
+}
+
+// New creates an empty state.
+func New() *State {
+	return &State{
+		LogScroll: -1,
+		Seen:      make(map[string]int),
+	}
+}
+
+// Store reads and writes the state file for a single Claude project.
+type Store struct {
+	path string
+}
+
+// NewStore creates a store for the given Claude project directory.
+// The state file is placed under the user's state directory and named after the project directory.
+func NewStore(claudeProjectPath string) (*Store, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(claudeProjectPath) + ".json"
+
+	return &Store{path: filepath.Join(dir, appName, "projects", name)}, nil
+}
+
+// Path returns the path of the state file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Load reads the state file.
+// A missing file is not an error and yields an empty state.
+func (s *Store) Load() (*State, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return New(), fmt.Errorf("failed to read state file %s: %w", s.path, err)
+	}
+
+	st := New()
+	if err := json.Unmarshal(data, st); err != nil {
+		return New(), fmt.Errorf("failed to parse state file %s: %w", s.path, err)
+	}
+	if st.Seen == nil {
+		st.Seen = make(map[string]int)
+	}
+
+	return st, nil
+}
+
+// Backup moves the state file aside, e.g. after it failed to load, so that saving does not overwrite it.
+// It returns the path the file was moved to.
+func (s *Store) Backup() (string, error) {
+	backup := s.path + ".broken"
+	if err := os.Rename(s.path, backup); err != nil {
+		return "", fmt.Errorf("failed to move state file %s aside: %w", s.path, err)
+	}
+
+	return backup, nil
+}
+
+// Save writes the state file atomically.
+func (s *Store) Save(st *State) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state file %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace state file %s: %w", s.path, err)
+	}
+
+	return nil
+}
+
+// stateDir returns the user's state directory.
+// $XDG_STATE_HOME is used when set, otherwise ~/.local/state.
+func stateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return dir, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	return filepath.Join(homeDir, ".local", "state"), nil
+}
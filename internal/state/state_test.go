@@ -0,0 +1,94 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	s, err := NewStore("/home/me/.claude/projects/-home-me-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestNewStoreNamesTheFileAfterTheProject(t *testing.T) {
+	s := newTestStore(t)
+	want := filepath.Join(os.Getenv("XDG_STATE_HOME"), appName, "projects", "-home-me-repo.json")
+	if s.Path() != want {
+		t.Fatalf("Path() = %q, want %q", s.Path(), want)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	st, err := newTestStore(t).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.LogScroll != -1 || st.Seen == nil {
+		t.Fatalf("state of a missing file = %+v, want a new state", st)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	s := newTestStore(t)
+	st := New()
+	st.TreeOrder = []string{"b", "a"}
+	st.SelectedSession = "a"
+	st.LogScroll = 12
+	st.Seen["a"] = 3
+	if err := s.Save(st); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got.TreeOrder, st.TreeOrder) || got.SelectedSession != "a" || got.LogScroll != 12 || got.Seen["a"] != 3 {
+		t.Fatalf("loaded %+v, want %+v", got, st)
+	}
+	if _, err := os.Stat(s.Path() + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestBackupMovesAnUnreadableFileAside(t *testing.T) {
+	s := newTestStore(t)
+	if err := os.MkdirAll(filepath.Dir(s.Path()), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(s.Path(), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := s.Load()
+	if err == nil {
+		t.Fatal("Load() of a corrupt file succeeded")
+	}
+	if st == nil || st.LogScroll != -1 {
+		t.Fatalf("Load() of a corrupt file = %+v, want a new state", st)
+	}
+
+	backup, err := s.Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(backup); err != nil || string(data) != "{not json" {
+		t.Fatalf("backup %s = %q, %v", backup, data, err)
+	}
+	if _, err := s.Load(); err != nil {
+		t.Fatalf("Load() after Backup() = %v", err)
+	}
+
+	if _, err := s.Backup(); err == nil {
+		t.Errorf("Backup() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,117 @@
+// Package stream prints session activity as a linear stream of plain text.
+// It is the non-interactive alternative to the TUI for screen readers and terminals without cursor addressing.
+package stream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+	"github.com/sters/cc-session-tailing/internal/render"
+	"github.com/sters/cc-session-tailing/internal/session"
+	"github.com/sters/cc-session-tailing/internal/watcher"
+)
+
+// Streamer writes new messages of all sessions to an output as they arrive.
+type Streamer struct {
+	manager  *session.Manager
+	watcher  *watcher.Watcher
+	out      io.Writer
+	renderer *render.Renderer
+	caches   map[string]*render.Cache // session ID -> rendered lines of its messages
+	width    int
+	last     string // session of the last printed message
+}
+
+// New creates a streamer that wraps messages at width columns.
+func New(manager *session.Manager, w *watcher.Watcher, out io.Writer, width int) *Streamer {
+	return &Streamer{
+		manager:  manager,
+		watcher:  w,
+		out:      out,
+		renderer: render.NewRenderer(nil),
+		caches:   make(map[string]*render.Cache),
+		width:    max(render.MinMarkdownWidth, width),
+	}
+}
+
+// Run prints new messages until ctx is done.
+// Messages that were already loaded into the manager are not printed.
+func (s *Streamer) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-s.watcher.Events:
+			if err := s.handle(event); err != nil {
+				return err
+			}
+		case err := <-s.watcher.Errors:
+			if _, werr := fmt.Fprintf(s.out, "watch error: %v\n", err); werr != nil {
+				return fmt.Errorf("failed to write stream: %w", werr)
+			}
+		}
+	}
+}
+
+// handle reads the new messages of an updated session file and prints them.
+func (s *Streamer) handle(event watcher.Event) error {
+	var sess *session.Session
+	if event.ParentID != "" {
+		sess = s.manager.GetOrCreateSessionWithParent(event.SessionID, event.Path, event.ParentID, event.IsSubagent)
+	} else {
+		sess = s.manager.GetOrCreateSession(event.SessionID, event.Path, event.IsSubagent)
+	}
+
+	messages, newOffset, err := parser.ParseFromOffset(event.Path, sess.Offset)
+	if err != nil || len(messages) == 0 {
+		return nil
+	}
+
+	first := len(sess.Messages)
+	s.manager.UpdateSession(event.SessionID, messages, newOffset)
+
+	return s.print(sess, first)
+}
+
+// print writes the messages of a session from index first on, preceded by a session line
+// when the previous output was about another session.
+func (s *Streamer) print(sess *session.Session, first int) error {
+	// The whole session is rendered so that tool results know their calls and turns their start;
+	// the cache renders only the messages that are new or changed by them.
+	cache, ok := s.caches[sess.ID]
+	if !ok {
+		cache = render.NewCache()
+		s.caches[sess.ID] = cache
+	}
+	doc := s.renderer.Session(sess.Messages, render.Options{
+		Width:      s.width,
+		Timestamps: render.TimestampsAbsolute,
+		Cache:      cache,
+	})
+	if first >= len(doc.MsgStarts) {
+		return nil
+	}
+
+	var b strings.Builder
+	if sess.ID != s.last {
+		kind := "session"
+		if sess.IsSubagent {
+			kind = "subagent"
+		}
+		fmt.Fprintf(&b, "\n== %s %s ==\n", kind, sess.ID)
+		s.last = sess.ID
+	}
+	for _, line := range doc.Lines[doc.MsgStarts[first]:] {
+		b.WriteString(strings.TrimRight(line.Plain(), " "))
+		b.WriteString("\n")
+	}
+
+	if _, err := io.WriteString(s.out, b.String()); err != nil {
+		return fmt.Errorf("failed to write stream: %w", err)
+	}
+
+	return nil
+}
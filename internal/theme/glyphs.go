@@ -0,0 +1,109 @@
+package theme
+
+import "github.com/charmbracelet/lipgloss"
+
+// Glyphs holds the symbols the UI draws with.
+type Glyphs struct {
+	Border      lipgloss.Border // panel and overlay borders
+	FocusBorder lipgloss.Border // border of the focused pane or panel
+	Branch      string          // tree branch to a child that has siblings below
+	LastBranch  string          // tree branch to the last child
+	Children    string          // session with subagents, collapsed
+	Expanded    string          // session with subagents, expanded
+	Activity    string          // session has new activity
+	Ellipsis    string          // truncated text
+	ScrollTrack string          // scrollbar track
+	ScrollThumb string          // scrollbar thumb
+	HLine       string          // horizontal rule
+	VLine       string          // vertical rule
+	Cross       string          // crossing of horizontal and vertical rules
+	TopLeft     string          // top-left corner of a frame
+	BottomLeft  string          // bottom-left corner of a frame
+	End         string          // end of a turn
+	Bullet      string          // list item
+	Separator   string          // separator between words
+	UpDown      string          // up and down keys in help text
+}
+
+//nolint:gochecknoglobals // package-level config
+var unicodeGlyphs = Glyphs{
+	Border:      lipgloss.RoundedBorder(),
+	FocusBorder: lipgloss.RoundedBorder(),
+	Branch:      "├─",
+	LastBranch:  "└─",
+	Children:    "▶",
+	Expanded:    "▼",
+	Activity:    "●",
+	Ellipsis:    "…",
+	ScrollTrack: "│",
+	ScrollThumb: "┃",
+	HLine:       "─",
+	VLine:       "│",
+	Cross:       "┼",
+	TopLeft:     "┌",
+	BottomLeft:  "└",
+	End:         "└",
+	Bullet:      "•",
+	Separator:   "·",
+	UpDown:      "↑/↓",
+}
+
+//nolint:gochecknoglobals // package-level config
+var asciiGlyphs = Glyphs{
+	Border: lipgloss.ASCIIBorder(),
+	FocusBorder: lipgloss.Border{
+		Top: "=", Bottom: "=", Left: "|", Right: "|",
+		TopLeft: "+", TopRight: "+", BottomLeft: "+", BottomRight: "+",
+		MiddleLeft: "+", MiddleRight: "+", Middle: "+", MiddleTop: "+", MiddleBottom: "+",
+	},
+	Branch:      "|-",
+	LastBranch:  "`-",
+	Children:    ">",
+	Expanded:    "v",
+	Activity:    "*",
+	Ellipsis:    "...",
+	ScrollTrack: "|",
+	ScrollThumb: "#",
+	HLine:       "-",
+	VLine:       "|",
+	Cross:       "+",
+	TopLeft:     "+",
+	BottomLeft:  "+",
+	End:         "`",
+	Bullet:      "*",
+	Separator:   ",",
+	UpDown:      "up/down",
+}
+
+//nolint:gochecknoglobals // package-level config
+var plain bool
+
+// SetPlain turns plain mode on or off.
+// Plain mode draws with ASCII glyphs and adds text labels for states that are otherwise shown only by color.
+func SetPlain(on bool) {
+	plain = on
+}
+
+// Plain returns whether plain mode is on.
+func Plain() bool {
+	return plain
+}
+
+// Symbols returns the glyphs of the current mode.
+func Symbols() Glyphs {
+	if plain {
+		return asciiGlyphs
+	}
+
+	return unicodeGlyphs
+}
+
+// BorderFor returns the border of a pane, marked in plain mode when the pane has focus.
+func BorderFor(focused bool) lipgloss.Border {
+	g := Symbols()
+	if focused {
+		return g.FocusBorder
+	}
+
+	return g.Border
+}
@@ -0,0 +1,238 @@
+// Package theme defines the color palettes and glyphs used by the TUI.
+// Every style is built from the colors of the current theme, so a theme change
+// before the UI is created recolors the whole application; views that keep styles
+// rebuild them when the theme is switched at runtime.
+package theme
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Auto is the theme name that picks the dark or light theme from the terminal background.
+const Auto = "auto"
+
+// Palette maps the color roles of the UI to terminal colors.
+// Colors are ANSI 256 indexes ("212") or hex RGB values ("#ff87d7").
+type Palette struct {
+	Accent          lipgloss.Color `json:"accent"`          // focus borders, titles, headers, cursors
+	Border          lipgloss.Color `json:"border"`          // unfocused borders and scrollbar tracks
+	Muted           lipgloss.Color `json:"muted"`           // help lines, labels, placeholders
+	Subtle          lipgloss.Color `json:"subtle"`          // thinking, tool details, subagents, times
+	Text            lipgloss.Color `json:"text"`            // body text
+	Strong          lipgloss.Color `json:"strong"`          // bold and heading text
+	Secondary       lipgloss.Color `json:"secondary"`       // tool input, code, quotes, diff context
+	HeaderBg        lipgloss.Color `json:"headerBg"`        // header bar background
+	OnAccent        lipgloss.Color `json:"onAccent"`        // text on the accent color
+	Highlight       lipgloss.Color `json:"highlight"`       // recent activity, unread sessions, search matches
+	HighlightStrong lipgloss.Color `json:"highlightStrong"` // the current search match
+	OnHighlight     lipgloss.Color `json:"onHighlight"`     // text on the highlight colors
+	Selection       lipgloss.Color `json:"selection"`       // selected rows in menus and search results
+	Tool            lipgloss.Color `json:"tool"`            // tool calls
+	User            lipgloss.Color `json:"user"`            // user messages and session names
+	Info            lipgloss.Color `json:"info"`            // links, headings, diff hunks
+	Success         lipgloss.Color `json:"success"`         // added lines, strings
+	Error           lipgloss.Color `json:"error"`           // removed lines, errors
+	Keyword         lipgloss.Color `json:"keyword"`         // code keywords
+	Number          lipgloss.Color `json:"number"`          // code numbers
+	Code            lipgloss.Color `json:"code"`            // inline code
+}
+
+// Theme is a named palette.
+type Theme struct {
+	Name    string
+	Palette Palette
+}
+
+// Spec describes a user theme: a built-in theme to start from and the colors that replace its colors.
+type Spec struct {
+	Base   string          `json:"base,omitempty"`
+	Colors json.RawMessage `json:"colors,omitempty"`
+}
+
+// colorPattern matches the accepted color values: an ANSI index from 0 to 255 or a hex value.
+var colorPattern = regexp.MustCompile(`^(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9]|#[0-9a-fA-F]{6}|#[0-9a-fA-F]{3})$`) //nolint:gochecknoglobals // package-level config
+
+//nolint:gochecknoglobals // package-level config
+var builtins = map[string]Palette{
+	"dark": {
+		Accent:          "212",
+		Border:          "240",
+		Muted:           "240",
+		Subtle:          "243",
+		Text:            "252",
+		Strong:          "255",
+		Secondary:       "250",
+		HeaderBg:        "235",
+		OnAccent:        "235",
+		Highlight:       "220",
+		HighlightStrong: "208",
+		OnHighlight:     "235",
+		Selection:       "237",
+		Tool:            "214",
+		User:            "117",
+		Info:            "75",
+		Success:         "114",
+		Error:           "210",
+		Keyword:         "176",
+		Number:          "215",
+		Code:            "180",
+	},
+	"light": {
+		Accent:          "162",
+		Border:          "248",
+		Muted:           "244",
+		Subtle:          "242",
+		Text:            "236",
+		Strong:          "232",
+		Secondary:       "239",
+		HeaderBg:        "254",
+		OnAccent:        "255",
+		Highlight:       "220",
+		HighlightStrong: "208",
+		OnHighlight:     "232",
+		Selection:       "253",
+		Tool:            "130",
+		User:            "25",
+		Info:            "26",
+		Success:         "28",
+		Error:           "160",
+		Keyword:         "90",
+		Number:          "130",
+		Code:            "94",
+	},
+	"high-contrast": {
+		Accent:          "13",
+		Border:          "15",
+		Muted:           "250",
+		Subtle:          "252",
+		Text:            "15",
+		Strong:          "15",
+		Secondary:       "15",
+		HeaderBg:        "0",
+		OnAccent:        "0",
+		Highlight:       "11",
+		HighlightStrong: "208",
+		OnHighlight:     "0",
+		Selection:       "238",
+		Tool:            "11",
+		User:            "14",
+		Info:            "12",
+		Success:         "10",
+		Error:           "9",
+		Keyword:         "13",
+		Number:          "11",
+		Code:            "11",
+	},
+}
+
+//nolint:gochecknoglobals // package-level config
+var current = Theme{Name: "dark", Palette: builtins["dark"]}
+
+// Current returns the theme in use.
+func Current() Theme {
+	return current
+}
+
+// Colors returns the palette of the theme in use.
+func Colors() Palette {
+	return current.Palette
+}
+
+// Set makes t the theme in use. Styles created afterwards use its colors.
+func Set(t Theme) {
+	current = t
+}
+
+// Builtin returns a built-in theme by name.
+func Builtin(name string) (Theme, bool) {
+	p, ok := builtins[name]
+
+	return Theme{Name: name, Palette: p}, ok
+}
+
+// Names returns the names of the built-in themes, sorted.
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Resolve returns the theme with the given name.
+// User themes take precedence over built-in themes of the same name.
+// "auto" and an empty name select the dark or light theme from the terminal background.
+func Resolve(name string, user map[string]Spec) (Theme, error) {
+	if name == "" || name == Auto {
+		name = detect()
+	}
+
+	if spec, ok := user[name]; ok {
+		return build(name, spec)
+	}
+	if t, ok := Builtin(name); ok {
+		return t, nil
+	}
+
+	return Theme{}, fmt.Errorf("unknown theme %q (built-in themes: %s)", name, strings.Join(Names(), ", "))
+}
+
+// detect returns "dark" or "light" depending on the terminal background.
+func detect() string {
+	if lipgloss.HasDarkBackground() {
+		return "dark"
+	}
+
+	return "light"
+}
+
+// build creates a user theme from its base theme and color overrides.
+func build(name string, spec Spec) (Theme, error) {
+	baseName := spec.Base
+	if baseName == "" || baseName == Auto {
+		baseName = detect()
+	}
+	base, ok := Builtin(baseName)
+	if !ok {
+		return Theme{}, fmt.Errorf("failed to build theme %q: unknown base theme %q", name, spec.Base)
+	}
+
+	palette := base.Palette
+	if len(spec.Colors) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(spec.Colors))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&palette); err != nil {
+			return Theme{}, fmt.Errorf("failed to parse colors of theme %q: %w", name, err)
+		}
+	}
+	if err := validate(palette); err != nil {
+		return Theme{}, fmt.Errorf("failed to build theme %q: %w", name, err)
+	}
+
+	return Theme{Name: name, Palette: palette}, nil
+}
+
+// validate checks that every color of a palette is an ANSI index (0-255) or a hex value.
+func validate(p Palette) error {
+	v := reflect.ValueOf(p)
+	for i := range v.NumField() {
+		color := v.Field(i).String()
+		if !colorPattern.MatchString(color) {
+			field := v.Type().Field(i)
+
+			return fmt.Errorf("invalid color %q for %s", color, field.Tag.Get("json"))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sters/cc-session-tailing/internal/filter"
+	"github.com/sters/cc-session-tailing/internal/render"
+	"github.com/sters/cc-session-tailing/internal/session"
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// exportWidth is the line width of exported session logs.
+const exportWidth = 100
+
+// paletteItems returns the sessions and commands listed by the palette.
+// Sessions come first, most recently updated first.
+func (m *Model) paletteItems() []paletteItem {
+	sessions := m.manager.GetAllSessions()
+	items := make([]paletteItem, 0, len(sessions)+16)
+	for _, sess := range sessions {
+		info := m.manager.Info(sess.ID)
+		kind := "session"
+		if sess.IsSubagent {
+			kind = "subagent"
+		}
+		fields := []string{sess.ID, info.SubagentType, info.Title, info.GitBranch, info.CWD}
+		fields = slices.DeleteFunc(fields, func(f string) bool { return f == "" })
+		text := strings.Join(fields, "  ")
+		items = append(items, paletteItem{kind: kind, text: text, session: sess.ID})
+	}
+
+	return append(items, m.paletteCommands()...)
+}
+
+// paletteCommands returns the commands listed by the palette, labeled with their current effect.
+func (m *Model) paletteCommands() []paletteItem {
+	k := m.keys
+	command := func(text, keyText string, run func() tea.Cmd) paletteItem {
+		return paletteItem{kind: "command", text: text, key: keyText, run: run}
+	}
+
+	otherMode := "panel"
+	if m.viewMode == ViewModePanel {
+		otherMode = "tree"
+	}
+	markdown := "on"
+	if m.markdown {
+		markdown = "off"
+	}
+	follow := "on"
+	if m.treeView.Follow() == FollowOn {
+		follow = "off"
+	}
+
+	items := []paletteItem{
+		command("Switch to "+otherMode+" mode", k.ToggleView.Help().Key, m.ToggleViewMode),
+		command("Open the filter menu", k.Filter.Help().Key, func() tea.Cmd {
+			m.openFilterMenu()
+
+			return nil
+		}),
+	}
+
+	f := m.activeFilter()
+	for i, kind := range filter.Kinds {
+		verb := "Hide"
+		if f.KindHidden(kind) {
+			verb = "Show"
+		}
+		keyText := ""
+		if keys := k.FilterKind.Keys(); k.FilterKind.Enabled() && i < len(keys) {
+			keyText = keys[i]
+		}
+		items = append(items, command(fmt.Sprintf("Filter: %s %s blocks", verb, kind), keyText, func() tea.Cmd {
+			f.ToggleKind(kind)
+			m.applyFilter()
+
+			return nil
+		}))
+	}
+
+	items = append(items,
+		command("Filter: Show all blocks", k.FilterReset.Help().Key, func() tea.Cmd {
+			f.Reset()
+			m.applyFilter()
+
+			return nil
+		}),
+		command("Turn Markdown rendering "+markdown, k.Markdown.Help().Key, func() tea.Cmd {
+			m.setMarkdown(!m.markdown)
+
+			return nil
+		}),
+		command("Timestamps: "+m.timestamps.Next().String(), k.Timestamps.Help().Key, func() tea.Cmd {
+			m.setTimestamps(m.timestamps.Next())
+
+			return nil
+		}),
+		command("Search all sessions", k.Search.Help().Key, func() tea.Cmd {
+			m.search.Open()
+
+			return nil
+		}),
+		command("Turn following the most active session "+follow, k.Follow.Help().Key, func() tea.Cmd {
+			m.treeView.ToggleFollow()
+			m.setNotice("Follow: " + m.treeView.Follow().String())
+
+			return nil
+		}),
+	)
+
+	for _, sortKey := range session.SortKeys() {
+		text := "Sort sessions by " + sortKey.String()
+		if sortKey == m.treeView.SortKey() {
+			text += " (current)"
+		}
+		items = append(items, command(text, "", func() tea.Cmd {
+			m.treeView.SetSort(sortKey)
+			m.setNotice("Sort: " + sortKey.String())
+
+			return nil
+		}))
+	}
+	for _, groupKey := range session.GroupKeys() {
+		text := "Group sessions by " + groupKey.String()
+		if groupKey == session.GroupNone {
+			text = "Do not group sessions"
+		}
+		if groupKey == m.treeView.GroupKey() {
+			text += " (current)"
+		}
+		items = append(items, command(text, "", func() tea.Cmd {
+			m.treeView.SetGroup(groupKey)
+			m.setNotice("Group: " + groupKey.String())
+
+			return nil
+		}))
+	}
+
+	items = append(items,
+		command("Export the current session to a text file", "", func() tea.Cmd {
+			m.exportCurrentSession()
+
+			return nil
+		}),
+		command("Show key bindings and colors", k.Help.Help().Key, func() tea.Cmd {
+			m.help.Open()
+
+			return nil
+		}),
+	)
+
+	// Colors are off in plain mode, so there is no theme to switch to.
+	if theme.Plain() {
+		return items
+	}
+	for _, name := range m.themeNames() {
+		text := "Theme: " + name
+		if name == theme.Current().Name {
+			text += " (current)"
+		}
+		items = append(items, command(text, "", func() tea.Cmd {
+			m.switchTheme(name)
+
+			return nil
+		}))
+	}
+
+	return items
+}
+
+// runPaletteItem jumps to the chosen session or runs the chosen command.
+func (m *Model) runPaletteItem(item *paletteItem) tea.Cmd {
+	if item.run != nil {
+		return item.run()
+	}
+	m.jumpToSession(item.session)
+
+	return nil
+}
+
+// jumpToSession shows a session in the current view mode.
+// In tree mode the session is selected and its log focused. In panel mode the panel showing it
+// is focused; a session without a panel is pinned to the focused panel.
+func (m *Model) jumpToSession(sessionID string) {
+	if m.viewMode == ViewModeTree {
+		m.treeView.OpenSession(sessionID, -1, "")
+
+		return
+	}
+
+	m.syncPanelFocus()
+	for i, sess := range m.manager.GetPanelSessions() {
+		if sess != nil && sess.ID == sessionID {
+			m.setFocusedPanel(i)
+			m.markPanelsRead()
+
+			return
+		}
+	}
+
+	m.zoomed = false
+	m.manager.PinToSlot(m.focusedPanel, sessionID)
+	m.setFocusedPanel(m.focusedPanel)
+	m.markPanelsRead()
+}
+
+// currentSession returns the session selected in tree mode or shown in the focused panel.
+func (m *Model) currentSession() *session.Session {
+	if m.viewMode == ViewModeTree {
+		return m.treeView.SelectedSession()
+	}
+	m.syncPanelFocus()
+
+	return m.focusedPanelSession()
+}
+
+// exportCurrentSession writes the current session to a text file in the working directory.
+func (m *Model) exportCurrentSession() {
+	sess := m.currentSession()
+	if sess == nil {
+		m.setNotice("No session to export")
+
+		return
+	}
+
+	path, err := exportSession(sess, m.activeFilter(), m.markdown, ".")
+	if err != nil {
+		m.setNotice(err.Error())
+
+		return
+	}
+	m.setNotice("Exported " + sess.ID + " to " + path)
+}
+
+// exportSession writes a session as plain text with message times to a file in dir
+// and returns the path of the file. Hidden block kinds are left out.
+func exportSession(sess *session.Session, f *filter.Filter, markdown bool, dir string) (string, error) {
+	doc := render.NewRenderer(nil).Session(sess.Messages, render.Options{
+		Width:      exportWidth,
+		Filter:     f,
+		Markdown:   markdown,
+		Timestamps: render.TimestampsAbsolute,
+		Now:        time.Now(),
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "session %s\n\n", sess.ID)
+	for _, line := range doc.Lines {
+		b.WriteString(strings.TrimRight(line.Plain(), " "))
+		b.WriteString("\n")
+	}
+
+	name := "session-" + strings.ReplaceAll(sess.ID, "/", "_") + ".txt"
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		return "", fmt.Errorf("failed to export session: %w", err)
+	}
+
+	return path, nil
+}
+
+// themeNames returns the names of the built-in and user themes, sorted.
+func (m *Model) themeNames() []string {
+	names := theme.Names()
+	for name := range m.themes {
+		if _, ok := theme.Builtin(name); !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// switchTheme makes the named theme current and rebuilds every style.
+func (m *Model) switchTheme(name string) {
+	t, err := theme.Resolve(name, m.themes)
+	if err != nil {
+		m.setNotice(err.Error())
+
+		return
+	}
+
+	theme.Set(t)
+	m.renderer.SetStyles(NewStyles())
+	m.treeView.Restyle()
+	m.setNotice("Theme: " + t.Name)
+}
+
+// setNotice shows a message instead of the help line until the next key.
+func (m *Model) setNotice(text string) {
+	m.notice = text
+	m.treeView.SetNotice(text)
+}
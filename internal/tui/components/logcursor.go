@@ -0,0 +1,249 @@
+package components
+
+import (
+	"strings"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+	"github.com/sters/cc-session-tailing/internal/render"
+)
+
+// blockKey identifies a content block by message index and block index.
+type blockKey struct {
+	msg   int
+	block int
+}
+
+// blockRef is the rendered line range of a content block.
+type blockRef struct {
+	key   blockKey
+	start int // first content line
+	end   int // last content line + 1
+}
+
+// NextBlock moves the block cursor to the next block.
+// Without a cursor, the first block in view is selected.
+func (l *LogViewport) NextBlock() {
+	l.moveCursor(1)
+}
+
+// PrevBlock moves the block cursor to the previous block.
+// Without a cursor, the first block in view is selected.
+func (l *LogViewport) PrevBlock() {
+	l.moveCursor(-1)
+}
+
+// HasCursor returns whether a block is under the cursor.
+func (l *LogViewport) HasCursor() bool {
+	return l.cursor != nil
+}
+
+// ClearCursor removes the block cursor.
+func (l *LogViewport) ClearCursor() {
+	l.setCursor(nil)
+	l.updateContent()
+}
+
+// ClickLine handles a click on the given row, counted from the top border of the viewport.
+// The block shown there is selected, or toggled when it is already selected.
+// Returns false if no block is shown there.
+func (l *LogViewport) ClickLine(row int) bool {
+	// Content starts below the border and the header.
+	const contentTop = 2
+	if row < contentTop || row >= contentTop+l.viewport.Height {
+		return false
+	}
+
+	line := l.top() + row - contentTop
+	for _, ref := range l.blocks {
+		if line < ref.start || line >= ref.end {
+			continue
+		}
+		if l.cursor != nil && *l.cursor == ref.key {
+			l.ToggleBlock()
+
+			return true
+		}
+		key := ref.key
+		l.setCursor(&key)
+		l.updateContent()
+
+		return true
+	}
+
+	return false
+}
+
+// ToggleBlock expands or collapses the block under the cursor.
+// Without a cursor, the first block in view is selected instead.
+func (l *LogViewport) ToggleBlock() {
+	idx := l.cursorIndex()
+	if idx < 0 {
+		l.moveCursor(0)
+
+		return
+	}
+
+	key := l.blocks[idx].key
+	l.expanded[key] = !l.isExpanded(key, l.block(key))
+	l.cache.Invalidate(key.msg)
+	l.updateContent()
+	l.scrollToCursor()
+}
+
+// ToggleExpandThinking expands or collapses all thinking blocks.
+func (l *LogViewport) ToggleExpandThinking() {
+	l.expandThink = !l.expandThink
+	l.resetExpanded(func(block parser.ContentBlock) bool {
+		return block.Type == "thinking"
+	})
+}
+
+// ToggleExpandToolIO expands or collapses all tool inputs and results.
+func (l *LogViewport) ToggleExpandToolIO() {
+	l.expandToolIO = !l.expandToolIO
+	l.resetExpanded(func(block parser.ContentBlock) bool {
+		return block.Type == "tool_use" || block.Type == "tool_result"
+	})
+}
+
+// resetExpanded drops per-block overrides of matching blocks so that the global setting applies to them.
+func (l *LogViewport) resetExpanded(match func(parser.ContentBlock) bool) {
+	for key := range l.expanded {
+		if match(l.block(key)) {
+			delete(l.expanded, key)
+		}
+	}
+	// The setting applies to every session; the lines kept for the others are rendered again when they are shown.
+	for _, view := range l.views {
+		view.cache = nil
+	}
+	l.cache.InvalidateAll()
+	l.updateContent()
+	l.scrollToCursor()
+}
+
+// isExpanded returns whether a block shows its full content.
+// A per-block toggle overrides the global settings.
+func (l *LogViewport) isExpanded(key blockKey, block parser.ContentBlock) bool {
+	if expanded, ok := l.expanded[key]; ok {
+		return expanded
+	}
+
+	switch block.Type {
+	case "thinking":
+		return l.expandThink
+	case "tool_use", "tool_result":
+		return l.expandToolIO
+	default:
+		return false
+	}
+}
+
+// block returns the content block identified by key.
+func (l *LogViewport) block(key blockKey) parser.ContentBlock {
+	if l.session == nil || key.msg >= len(l.session.Messages) {
+		return parser.ContentBlock{}
+	}
+	content := l.session.Messages[key.msg].Message.Content
+	if key.block >= len(content) {
+		return parser.ContentBlock{}
+	}
+
+	return content[key.block]
+}
+
+// setCursor moves the cursor to a block, or removes it when key is nil.
+// The messages of the blocks it leaves and enters are rendered again.
+func (l *LogViewport) setCursor(key *blockKey) {
+	if l.cursor != nil {
+		l.cache.Invalidate(l.cursor.msg)
+	}
+	if key != nil {
+		l.cache.Invalidate(key.msg)
+	}
+	l.cursor = key
+}
+
+// cursorIndex returns the index in l.blocks of the block under the cursor, or -1.
+func (l *LogViewport) cursorIndex() int {
+	if l.cursor == nil {
+		return -1
+	}
+	for i, ref := range l.blocks {
+		if ref.key == *l.cursor {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// moveCursor moves the cursor by delta blocks.
+// Without a cursor (or when its block is filtered out), the first block in view is selected.
+func (l *LogViewport) moveCursor(delta int) {
+	if len(l.blocks) == 0 {
+		return
+	}
+
+	idx := l.cursorIndex()
+	if idx < 0 {
+		idx = l.firstVisibleBlock()
+	} else {
+		idx = max(0, min(idx+delta, len(l.blocks)-1))
+	}
+
+	key := l.blocks[idx].key
+	l.setCursor(&key)
+	l.updateContent()
+	l.scrollToCursor()
+}
+
+// firstVisibleBlock returns the index of the first block that ends below the top of the viewport.
+func (l *LogViewport) firstVisibleBlock() int {
+	for i, ref := range l.blocks {
+		if ref.end > l.top() {
+			return i
+		}
+	}
+
+	return len(l.blocks) - 1
+}
+
+// scrollToCursor scrolls so that the first line of the block under the cursor is visible.
+func (l *LogViewport) scrollToCursor() {
+	idx := l.cursorIndex()
+	if idx < 0 {
+		return
+	}
+
+	ref := l.blocks[idx]
+	switch {
+	case ref.start < l.top():
+		l.scrollTo(ref.start)
+	case ref.end > l.top()+l.viewport.Height:
+		// Show as much of the block as fits, starting from its first line.
+		l.scrollTo(min(ref.start, ref.end-l.viewport.Height))
+	}
+}
+
+// expandStatus summarizes the global expand, Markdown and timestamp settings for the header.
+func (l *LogViewport) expandStatus() string {
+	var parts []string
+	if l.expandThink {
+		parts = append(parts, "+think")
+	}
+	if l.expandToolIO {
+		parts = append(parts, "+io")
+	}
+	if l.markdown {
+		parts = append(parts, "md")
+	}
+	switch l.timestamps {
+	case render.TimestampsAbsolute:
+		parts = append(parts, "time")
+	case render.TimestampsRelative:
+		parts = append(parts, "age")
+	}
+
+	return strings.Join(parts, " ")
+}
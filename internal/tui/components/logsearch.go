@@ -0,0 +1,342 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/sters/cc-session-tailing/internal/render"
+	"github.com/sters/cc-session-tailing/internal/search"
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// searchMatch is a single match position in the plain (unstyled) content lines.
+// A hidden match stands for the matches in the text of a collapsed block that are not
+// rendered, e.g. cut off by an ellipsis; it is on the last line of the block and has no offsets.
+type searchMatch struct {
+	line   int
+	start  int // byte offset in the plain line
+	end    int // byte offset in the plain line (exclusive)
+	hidden *blockKey
+}
+
+// logSearch holds the incremental search state of a LogViewport.
+type logSearch struct {
+	active  bool // typing the query
+	query   string
+	matches []searchMatch
+	current int // index into matches
+}
+
+// searchStyles holds styles for search highlighting.
+type searchStyles struct {
+	match   lipgloss.Style
+	current lipgloss.Style
+}
+
+func newSearchStyles() *searchStyles {
+	c := theme.Colors()
+
+	return &searchStyles{
+		match: lipgloss.NewStyle().
+			Background(c.Highlight).
+			Foreground(c.OnHighlight),
+		current: lipgloss.NewStyle().
+			Background(c.HighlightStrong).
+			Foreground(c.OnHighlight).
+			Bold(true),
+	}
+}
+
+// StartSearch opens the search prompt with an empty query.
+func (l *LogViewport) StartSearch() {
+	l.search = logSearch{active: true}
+	l.applyContent()
+}
+
+// SetSearchQuery highlights a query without opening the prompt.
+// The match nearest to the current scroll position becomes the current match.
+func (l *LogViewport) SetSearchQuery(query string) {
+	l.search = logSearch{query: query}
+	l.findMatches(0)
+	l.selectMatchNear(l.top())
+	l.applyContent()
+}
+
+// Searching returns whether the search prompt is accepting input.
+func (l *LogViewport) Searching() bool {
+	return l.search.active
+}
+
+// HasSearch returns whether a search query is set.
+func (l *LogViewport) HasSearch() bool {
+	return l.search.query != ""
+}
+
+// ClearSearch removes the search query and its highlights.
+func (l *LogViewport) ClearSearch() {
+	l.search = logSearch{}
+	l.applyContent()
+}
+
+// NextMatch moves to the next search match, wrapping around.
+func (l *LogViewport) NextMatch() {
+	l.moveMatch(1)
+}
+
+// PrevMatch moves to the previous search match, wrapping around.
+func (l *LogViewport) PrevMatch() {
+	l.moveMatch(-1)
+}
+
+func (l *LogViewport) moveMatch(delta int) {
+	n := len(l.search.matches)
+	if n == 0 {
+		return
+	}
+
+	l.search.current = ((l.search.current+delta)%n + n) % n
+	l.applyContent()
+	l.scrollToMatch()
+}
+
+// updateSearch handles a key while the search prompt is active.
+// The search is incremental: matches are updated on every keystroke.
+func (l *LogViewport) updateSearch(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		l.search.active = false
+		if l.search.query == "" {
+			l.ClearSearch()
+
+			return
+		}
+		l.scrollToMatch()
+
+		return
+	case tea.KeyEsc:
+		l.ClearSearch()
+
+		return
+	case tea.KeyBackspace:
+		if l.search.query == "" {
+			l.ClearSearch()
+
+			return
+		}
+		runes := []rune(l.search.query)
+		l.search.query = string(runes[:len(runes)-1])
+	case tea.KeySpace:
+		l.search.query += " "
+	case tea.KeyRunes:
+		l.search.query += string(msg.Runes)
+	default:
+		return
+	}
+
+	l.findMatches(0)
+	l.selectMatchNear(l.top())
+	l.applyContent()
+	l.scrollToMatch()
+}
+
+// findMatches finds the matches of the query in the plain content lines from line from onwards,
+// and adds a hidden match for each collapsed block there whose text has more matches than are rendered.
+// The matches on the lines above are kept; they are the same as long as those lines did not change.
+// Matching is case-insensitive unless the query contains an upper-case letter.
+func (l *LogViewport) findMatches(from int) {
+	query := l.search.query
+	if query == "" {
+		l.search.matches = nil
+
+		return
+	}
+	// Matches are found in the text as it is, so that their offsets are valid in the plain lines
+	// even where folding the case changes the length of a character.
+	matcher, err := search.Compile(search.Query{Pattern: query})
+	if err != nil {
+		l.search.matches = nil
+
+		return
+	}
+	kept := sort.Search(len(l.search.matches), func(i int) bool { return l.search.matches[i].line >= from })
+	l.search.matches = l.search.matches[:kept]
+
+	for i := from; i < len(l.lines); i++ {
+		for _, loc := range matcher.FindAll(l.lines[i].Plain()) {
+			l.search.matches = append(l.search.matches, searchMatch{line: i, start: loc[0], end: loc[1]})
+		}
+	}
+
+	var hidden []searchMatch
+	first := sort.Search(len(l.blocks), func(i int) bool { return l.blocks[i].start >= from })
+	for _, b := range l.blocks[first:] {
+		block := l.block(b.key)
+		if b.end <= b.start || l.isExpanded(b.key, block) {
+			continue
+		}
+		if len(matcher.FindAll(block.PlainText())) > l.matchesIn(b.start, b.end) {
+			key := b.key
+			hidden = append(hidden, searchMatch{line: b.end - 1, start: -1, end: -1, hidden: &key})
+		}
+	}
+	if len(hidden) > 0 {
+		// Keep matches in line order; a hidden match follows the rendered matches of its block.
+		l.search.matches = append(l.search.matches, hidden...)
+		found := l.search.matches[kept:]
+		sort.SliceStable(found, func(i, j int) bool {
+			return found[i].line < found[j].line
+		})
+	}
+
+	if l.search.current >= len(l.search.matches) {
+		l.search.current = max(0, len(l.search.matches)-1)
+	}
+}
+
+// matchesIn returns the number of rendered matches on the lines from start to end.
+func (l *LogViewport) matchesIn(start, end int) int {
+	matches := l.search.matches
+	first := sort.Search(len(matches), func(i int) bool { return matches[i].line >= start })
+	count := 0
+	for _, m := range matches[first:] {
+		if m.line >= end {
+			break
+		}
+		if m.hidden == nil {
+			count++
+		}
+	}
+
+	return count
+}
+
+// revealMatch expands the block of the current match if it is hidden,
+// and selects the first match in the block that was not rendered before.
+func (l *LogViewport) revealMatch() {
+	if len(l.search.matches) == 0 {
+		return
+	}
+	m := l.search.matches[l.search.current]
+	if m.hidden == nil {
+		return
+	}
+
+	// Matches rendered before the block was expanded keep their place; the next one is new.
+	seen := l.matchesIn(l.blockStart(*m.hidden), m.line+1)
+	l.expanded[*m.hidden] = true
+	l.cache.Invalidate(m.hidden.msg)
+	l.updateContent()
+	start := l.blockStart(*m.hidden)
+	l.selectMatchNear(start)
+	l.search.current = min(l.search.current+seen, len(l.search.matches)-1)
+	l.applyContent()
+}
+
+// blockStart returns the first content line of a rendered block, or 0.
+func (l *LogViewport) blockStart(key blockKey) int {
+	for _, b := range l.blocks {
+		if b.key == key {
+			return b.start
+		}
+	}
+
+	return 0
+}
+
+// selectMatchNear selects the first match at or after the given line, wrapping to the first match.
+func (l *LogViewport) selectMatchNear(line int) {
+	l.search.current = 0
+	for i, m := range l.search.matches {
+		if m.line >= line {
+			l.search.current = i
+
+			return
+		}
+	}
+}
+
+// scrollToMatch scrolls so that the current match is visible, leaving some context above it.
+// Once the query is entered, a hidden match is revealed by expanding its block.
+func (l *LogViewport) scrollToMatch() {
+	if !l.search.active {
+		l.revealMatch()
+	}
+	if len(l.search.matches) == 0 {
+		return
+	}
+
+	line := l.search.matches[l.search.current].line
+	if line >= l.top() && line < l.top()+l.viewport.Height {
+		return
+	}
+	l.scrollTo(max(0, line-l.viewport.Height/3))
+}
+
+// highlightedLines returns the content lines from start to end with search matches highlighted.
+// Lines containing a match are re-rendered from their plain text.
+func (l *LogViewport) highlightedLines(start, end int) []string {
+	lines := render.Strings(l.lines[start:end])
+
+	first := sort.Search(len(l.search.matches), func(i int) bool { return l.search.matches[i].line >= start })
+	for i := first; i < len(l.search.matches) && l.search.matches[i].line < end; {
+		lineIdx := l.search.matches[i].line
+		plain := l.lines[lineIdx].Plain()
+
+		var b strings.Builder
+		pos := 0
+		for ; i < len(l.search.matches) && l.search.matches[i].line == lineIdx; i++ {
+			m := l.search.matches[i]
+			if m.hidden != nil {
+				continue
+			}
+			b.WriteString(plain[pos:m.start])
+			style := l.searchStyles.match
+			if i == l.search.current {
+				style = l.searchStyles.current
+			}
+			text := plain[m.start:m.end]
+			if theme.Plain() {
+				// Matches are bracketed instead of colored; the current match is doubled.
+				text = "[" + text + "]"
+				if i == l.search.current {
+					text = "[" + text + "]"
+				}
+			}
+			b.WriteString(style.Render(text))
+			pos = m.end
+		}
+		b.WriteString(plain[pos:])
+		lines[lineIdx-start] = b.String()
+		if theme.Plain() {
+			// Brackets widen the line; cut the padding so it still fits.
+			lines[lineIdx-start] = runewidth.Truncate(strings.TrimRight(lines[lineIdx-start], " "), l.viewport.Width, "")
+		}
+	}
+
+	return lines
+}
+
+// searchStatus returns the search prompt or match counter for the header.
+func (l *LogViewport) searchStatus() string {
+	if !l.search.active && l.search.query == "" {
+		return ""
+	}
+
+	cursor := ""
+	if l.search.active {
+		cursor = "_"
+	}
+
+	if l.search.query == "" {
+		return "/" + cursor
+	}
+	if len(l.search.matches) == 0 {
+		return fmt.Sprintf("/%s%s  no matches", l.search.query, cursor)
+	}
+
+	return fmt.Sprintf("/%s%s  %d/%d", l.search.query, cursor, l.search.current+1, len(l.search.matches))
+}
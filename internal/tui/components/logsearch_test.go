@@ -0,0 +1,60 @@
+package components
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/sters/cc-session-tailing/internal/parser"
+	"github.com/sters/cc-session-tailing/internal/session"
+)
+
+func TestFindMatchesKeepsOffsetsOfTheOriginalText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  []string // matched text, in order
+	}{
+		{name: "lowercasing grows the line", text: "ȺȺȺ abc", query: "abc", want: []string{"abc"}},
+		{name: "folded match of a growing rune", text: "ȺȺȺ abc", query: "ⱥ", want: []string{"Ⱥ", "Ⱥ", "Ⱥ"}},
+		{name: "lowercasing shrinks the line", text: "İİ abc İ", query: "abc", want: []string{"abc"}},
+		{name: "upper-case query is case-sensitive", text: "abc ABC", query: "ABC", want: []string{"ABC"}},
+		{name: "no match", text: "İstanbul", query: "xyz", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLogViewport()
+			l.SetSize(80, 20)
+			l.SetSession(&session.Session{ID: "s", Messages: []parser.Message{{
+				Type:    "user",
+				Message: parser.MessageContent{Content: []parser.ContentBlock{{Type: "text", Text: tt.text}}},
+			}}})
+			l.SetSearchQuery(tt.query)
+
+			var got []string
+			for _, m := range l.search.matches {
+				plain := l.lines[m.line].Plain()
+				if m.start < 0 || m.end > len(plain) || m.start > m.end {
+					t.Fatalf("match %d-%d out of range of %q", m.start, m.end, plain)
+				}
+				text := plain[m.start:m.end]
+				if !utf8.ValidString(text) {
+					t.Fatalf("match %q splits a character", text)
+				}
+				got = append(got, text)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("matches = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("match %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+
+			// Highlighting slices the plain lines at the match offsets.
+			l.highlightedLines(0, len(l.lines))
+		})
+	}
+}
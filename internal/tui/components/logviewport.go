@@ -1,57 +1,61 @@
 package components
 
 import (
-	"encoding/json"
-	"fmt"
-	"sort"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-runewidth"
+	"github.com/sters/cc-session-tailing/internal/filter"
 	"github.com/sters/cc-session-tailing/internal/parser"
+	"github.com/sters/cc-session-tailing/internal/render"
 	"github.com/sters/cc-session-tailing/internal/session"
+	"github.com/sters/cc-session-tailing/internal/theme"
 )
 
-// logStyles holds styles for log rendering.
-type logStyles struct {
-	thinkStyle     lipgloss.Style
-	textStyle      lipgloss.Style
-	toolStyle      lipgloss.Style
-	toolInputStyle lipgloss.Style
-	userStyle      lipgloss.Style
-	labelStyle     lipgloss.Style
-}
-
-func newLogStyles() *logStyles {
-	return &logStyles{
-		thinkStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("243")).
-			Italic(true),
-		textStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("252")),
-		toolStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("214")).
-			Bold(true),
-		toolInputStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("250")),
-		userStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("117")).
-			Bold(true),
-		labelStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")),
-	}
+// collapsedDiffLines is the maximum number of diff lines shown for a collapsed file edit.
+const collapsedDiffLines = 20
+
+// windowMargin is how many pages above and below the visible lines are given to the viewport ahead of scrolling.
+const windowMargin = 2
+
+// cachedSessions is the number of recently shown sessions whose rendered lines are kept.
+const cachedSessions = 8
+
+// sessionView is what the log remembers of a session it showed.
+type sessionView struct {
+	scroll   int               // scroll offset; -1 = follow the bottom
+	expanded map[blockKey]bool // per-block expand overrides
+	cache    *render.Cache     // rendered lines; nil once dropped for more recent sessions
 }
 
 // LogViewport displays log content for a session.
 type LogViewport struct {
-	viewport viewport.Model
-	session  *session.Session
-	styles   *logStyles
-	width    int
-	height   int
-	focused  bool
+	viewport     viewport.Model
+	session      *session.Session
+	content      *render.Renderer
+	width        int
+	height       int
+	focused      bool
+	msgStarts    []int         // first content line of each message
+	lines        []render.Line // rendered content lines
+	cache        *render.Cache // rendered lines of each message of the session
+	window       [2]int        // range of lines given to the viewport; its offset is relative to the first
+	search       logSearch
+	searchStyles *searchStyles
+	filter       *filter.Filter
+	blocks       []blockRef // rendered blocks in display order
+	cursor       *blockKey  // block under the cursor; nil = no cursor
+	expanded     map[blockKey]bool
+	views        map[string]*sessionView // session ID -> state when it was last shown
+	recent       []string                // IDs of the sessions whose cache is kept, most recent last
+	expandThink  bool                    // expand all thinking blocks
+	expandToolIO bool                    // expand all tool inputs and results
+	markdown     bool                    // render assistant text as Markdown
+	timestamps   render.TimestampMode
 }
 
 // NewLogViewport creates a new log viewport.
@@ -60,8 +64,13 @@ func NewLogViewport() *LogViewport {
 	vp.SetContent("")
 
 	return &LogViewport{
-		viewport: vp,
-		styles:   newLogStyles(),
+		viewport:     vp,
+		content:      render.NewRenderer(nil),
+		cache:        render.NewCache(),
+		searchStyles: newSearchStyles(),
+		filter:       filter.New(),
+		expanded:     make(map[blockKey]bool),
+		views:        make(map[string]*sessionView),
 	}
 }
 
@@ -72,12 +81,88 @@ func (l *LogViewport) SetSize(width, height int) {
 	// Account for border and scrollbar.
 	l.viewport.Width = width - 3   // border (2) + scrollbar (1)
 	l.viewport.Height = height - 4 // border + header
+	l.ensureWindow()
 }
 
 // SetSession sets the session to display.
+// The scroll position and expanded blocks of the previous session are remembered, and a session
+// shown before returns to them: the same offset, or the bottom if it was following it.
+// The rendered lines of the last few sessions are kept too, so switching back does not render them again.
+// A session shown for the first time starts at the bottom.
 func (l *LogViewport) SetSession(s *session.Session) {
+	switched := s == nil || l.session == nil || s.ID != l.session.ID
+	offset := -1
+	if switched {
+		// The cursor belongs to the previous session.
+		l.setCursor(nil)
+		if l.session != nil {
+			l.views[l.session.ID] = &sessionView{scroll: l.YOffset(), expanded: l.expanded, cache: l.cache}
+		}
+		// The search matches are in the lines of the previous session.
+		l.lines = nil
+		l.search.matches = nil
+		l.expanded = make(map[blockKey]bool)
+		l.cache = render.NewCache()
+		if s != nil {
+			if view, ok := l.views[s.ID]; ok {
+				offset = view.scroll
+				l.expanded = view.expanded
+				if view.cache != nil {
+					l.cache = view.cache
+				}
+			}
+			l.keepCache(s.ID)
+		}
+	}
 	l.session = s
 	l.updateContent()
+
+	if switched && s != nil {
+		l.SetYOffset(offset)
+	}
+}
+
+// keepCache marks the cache of a session as most recently used,
+// dropping the caches of the sessions shown least recently beyond cachedSessions.
+func (l *LogViewport) keepCache(id string) {
+	l.recent = slices.DeleteFunc(l.recent, func(r string) bool { return r == id })
+	l.recent = append(l.recent, id)
+	for len(l.recent) > cachedSessions {
+		if view, ok := l.views[l.recent[0]]; ok {
+			view.cache = nil
+		}
+		l.recent = l.recent[1:]
+	}
+}
+
+// Filter returns the filter that selects which blocks are shown.
+// Call Refresh after changing it.
+func (l *LogViewport) Filter() *filter.Filter {
+	return l.filter
+}
+
+// SetMarkdown sets whether assistant text is rendered as Markdown.
+func (l *LogViewport) SetMarkdown(on bool) {
+	l.markdown = on
+	l.updateContent()
+}
+
+// SetTimestamps sets how message times are shown.
+func (l *LogViewport) SetTimestamps(mode render.TimestampMode) {
+	l.timestamps = mode
+	l.updateContent()
+}
+
+// Restyle rebuilds the styles from the current theme and re-renders the content.
+func (l *LogViewport) Restyle() {
+	l.content = render.NewRenderer(nil)
+	l.searchStyles = newSearchStyles()
+	l.updateContent()
+}
+
+// SetKeyMap sets the keys that scroll the viewport.
+func (l *LogViewport) SetKeyMap(km viewport.KeyMap) {
+	l.viewport.KeyMap = km
 }
 
 // SetFocused sets the focus state.
@@ -96,28 +181,36 @@ func (l *LogViewport) Update(msg tea.Msg) tea.Cmd {
 		return nil
 	}
 
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && l.search.active {
+		l.updateSearch(keyMsg)
+
+		return nil
+	}
+
 	var cmd tea.Cmd
 	l.viewport, cmd = l.viewport.Update(msg)
+	l.ensureWindow()
 
 	return cmd
 }
 
 // View renders the viewport.
 func (l *LogViewport) View() string {
-	borderColor := lipgloss.Color("240")
+	c := theme.Colors()
+	borderColor := c.Border
 	if l.focused {
-		borderColor = lipgloss.Color("212")
+		borderColor = c.Accent
 	}
 
 	borderStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
+		Border(theme.BorderFor(l.focused)).
 		BorderForeground(borderColor).
 		Width(l.width - 2).
 		Height(l.height - 2)
 
 	if l.session == nil {
 		emptyStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
+			Foreground(c.Muted).
 			Italic(true)
 		content := lipgloss.Place(
 			l.width-4,
@@ -133,8 +226,8 @@ func (l *LogViewport) View() string {
 	// Header.
 	headerStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("212")).
-		Background(lipgloss.Color("235")).
+		Foreground(c.Accent).
+		Background(c.HeaderBg).
 		Padding(0, 1).
 		Width(l.width - 5) // Account for scrollbar.
 
@@ -142,7 +235,19 @@ func (l *LogViewport) View() string {
 	if l.session.IsSubagent {
 		prefix = "[SUB] "
 	}
-	header := headerStyle.Render(prefix + l.session.ID)
+	title := prefix + l.session.ID
+	if summary := l.filter.String(); summary != "" {
+		title += "  " + summary
+	}
+	if summary := l.expandStatus(); summary != "" {
+		title += "  " + summary
+	}
+	if status := l.searchStatus(); status != "" {
+		// Keep the search status visible; shorten the title instead.
+		available := l.width - 7 - runewidth.StringWidth(status) - 2
+		title = runewidth.Truncate(title, max(0, available), theme.Symbols().Ellipsis) + "  " + status
+	}
+	header := headerStyle.Render(title)
 
 	// Render scrollbar.
 	scrollbar := l.renderScrollbar()
@@ -158,19 +263,21 @@ func (l *LogViewport) View() string {
 
 // renderScrollbar renders a scrollbar indicator.
 func (l *LogViewport) renderScrollbar() string {
+	c := theme.Colors()
 	height := l.viewport.Height
-	totalLines := l.viewport.TotalLineCount()
+	totalLines := len(l.lines)
 	visibleLines := l.viewport.Height
-	yOffset := l.viewport.YOffset
+	yOffset := l.top()
 
-	scrollbarStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	thumbStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	g := theme.Symbols()
+	scrollbarStyle := lipgloss.NewStyle().Foreground(c.Border)
+	thumbStyle := lipgloss.NewStyle().Foreground(c.Accent)
 
 	// If all content fits, show empty track.
 	if totalLines <= visibleLines {
 		var lines []string
 		for range height {
-			lines = append(lines, scrollbarStyle.Render("│"))
+			lines = append(lines, scrollbarStyle.Render(g.ScrollTrack))
 		}
 
 		return strings.Join(lines, "\n")
@@ -187,9 +294,9 @@ func (l *LogViewport) renderScrollbar() string {
 	var lines []string
 	for i := range height {
 		if i >= thumbPos && i < thumbPos+thumbHeight {
-			lines = append(lines, thumbStyle.Render("┃"))
+			lines = append(lines, thumbStyle.Render(g.ScrollThumb))
 		} else {
-			lines = append(lines, scrollbarStyle.Render("│"))
+			lines = append(lines, scrollbarStyle.Render(g.ScrollTrack))
 		}
 	}
 
@@ -198,241 +305,178 @@ func (l *LogViewport) renderScrollbar() string {
 
 // ScrollDown scrolls the viewport down.
 func (l *LogViewport) ScrollDown() {
-	l.viewport.ScrollDown(1)
+	l.ScrollBy(1)
 }
 
 // ScrollUp scrolls the viewport up.
 func (l *LogViewport) ScrollUp() {
-	l.viewport.ScrollUp(1)
+	l.ScrollBy(-1)
+}
+
+// ScrollBy scrolls the viewport by delta lines (positive = down).
+func (l *LogViewport) ScrollBy(delta int) {
+	if delta < 0 {
+		l.viewport.ScrollUp(-delta)
+	} else {
+		l.viewport.ScrollDown(delta)
+	}
+	l.ensureWindow()
 }
 
 // GotoBottom scrolls to the bottom of the content.
 func (l *LogViewport) GotoBottom() {
-	l.viewport.GotoBottom()
+	l.scrollTo(len(l.lines))
 }
 
-// updateContent updates the viewport content from the session.
-func (l *LogViewport) updateContent() {
-	if l.session == nil {
-		l.viewport.SetContent("")
-
-		return
+// YOffset returns the current scroll offset, or -1 when following the bottom.
+func (l *LogViewport) YOffset() int {
+	if l.atBottom() {
+		return -1
 	}
 
-	// Check if we're at the bottom before updating content.
-	wasAtBottom := l.viewport.AtBottom()
+	return l.top()
+}
 
-	contentWidth := l.width - 5 // border (2) + scrollbar (1) + padding (2)
+// SetYOffset sets the scroll offset. A negative offset follows the bottom.
+func (l *LogViewport) SetYOffset(offset int) {
+	if offset < 0 {
+		l.GotoBottom()
 
-	var lines []string
-	for _, msg := range l.session.Messages {
-		msgLines := l.renderMessage(msg, contentWidth)
-		lines = append(lines, msgLines...)
+		return
 	}
 
-	content := strings.Join(lines, "\n")
-	l.viewport.SetContent(content)
-
-	// Only scroll to bottom if we were already at the bottom.
-	if wasAtBottom {
-		l.viewport.GotoBottom()
-	}
+	l.scrollTo(offset)
 }
 
-func (l *LogViewport) renderMessage(msg parser.Message, width int) []string {
-	var lines []string
-
-	for _, block := range msg.Message.Content {
-		blockLines := l.renderContentBlock(block, width, msg.Type)
-		lines = append(lines, blockLines...)
+// ScrollToMessage scrolls so that the given message is at the top of the viewport.
+func (l *LogViewport) ScrollToMessage(index int) {
+	if index < 0 || index >= len(l.msgStarts) {
+		return
 	}
 
-	return lines
+	l.scrollTo(l.msgStarts[index])
 }
 
-func (l *LogViewport) renderContentBlock(block parser.ContentBlock, width int, msgType string) []string {
-	var lines []string
+// top returns the first visible content line.
+func (l *LogViewport) top() int {
+	return l.window[0] + l.viewport.YOffset
+}
 
-	// Handle user messages.
-	if msgType == "user" {
-		if block.Type == "text" && block.Text != "" {
-			label := l.styles.labelStyle.Render("[USER] ")
-			wrapped := wrapText(block.Text, width-7)
-			for i, line := range wrapped {
-				if i == 0 {
-					lines = append(lines, label+l.styles.userStyle.Render(line))
-				} else {
-					lines = append(lines, "       "+l.styles.userStyle.Render(line))
-				}
-			}
-		}
+// atBottom returns whether the last content line is visible.
+func (l *LogViewport) atBottom() bool {
+	return l.top() >= len(l.lines)-l.viewport.Height
+}
 
-		return lines
+// ReadCount returns the number of messages the user has viewed so far,
+// i.e. the index of the last message visible in the viewport + 1.
+func (l *LogViewport) ReadCount() int {
+	if l.session == nil {
+		return 0
+	}
+	if l.atBottom() {
+		return len(l.msgStarts)
 	}
 
-	switch block.Type {
-	case "thinking":
-		text := block.Thinking
-		if text == "" {
-			text = block.Text
-		}
-		if text != "" {
-			label := l.styles.labelStyle.Render("[THINK] ")
-			content := l.styles.thinkStyle.Render(truncateText(text, width-8))
-			lines = append(lines, label+content)
-		}
-
-	case "text":
-		if block.Text != "" {
-			label := l.styles.labelStyle.Render("[TEXT] ")
-			wrapped := wrapText(block.Text, width-7)
-			for i, line := range wrapped {
-				if i == 0 {
-					lines = append(lines, label+l.styles.textStyle.Render(line))
-				} else {
-					lines = append(lines, "       "+l.styles.textStyle.Render(line))
-				}
-			}
-		}
-
-	case "tool_use":
-		label := l.styles.labelStyle.Render("[TOOL] ")
-		toolName := l.styles.toolStyle.Render(block.Name)
-		lines = append(lines, label+toolName)
-
-		// Show tool input.
-		if block.Input != nil {
-			inputStr := formatToolInput(block.Input, width-7)
-			for _, line := range inputStr {
-				lines = append(lines, "       "+l.styles.toolInputStyle.Render(line))
-			}
-		}
-
-	case "tool_result":
-		label := l.styles.labelStyle.Render("[RESULT] ")
-		if block.Text != "" {
-			content := truncateText(block.Text, width-9)
-			lines = append(lines, label+l.styles.textStyle.Render(content))
+	bottom := l.top() + l.viewport.Height
+	count := 0
+	for i, start := range l.msgStarts {
+		if start >= bottom {
+			break
 		}
+		count = i + 1
 	}
 
-	return lines
+	return count
 }
 
-// Refresh updates the content from the current session.
-func (l *LogViewport) Refresh() {
-	l.updateContent()
-}
-
-func truncateText(text string, maxWidth int) string {
-	text = strings.ReplaceAll(text, "\n", " ")
-	text = strings.ReplaceAll(text, "\r", "")
-
-	if runewidth.StringWidth(text) > maxWidth {
-		return runewidth.Truncate(text, maxWidth-3, "...")
-	}
-
-	return text
-}
+// updateContent updates the viewport content from the session.
+// Only the messages added or changed since the last update are rendered.
+func (l *LogViewport) updateContent() {
+	if l.session == nil {
+		l.lines = nil
+		l.findMatches(0)
+		l.window = [2]int{}
+		l.viewport.SetContent("")
 
-func wrapText(text string, width int) []string {
-	if width <= 0 {
-		return []string{text}
+		return
 	}
 
-	var lines []string
-	text = strings.ReplaceAll(text, "\r", "")
-	paragraphs := strings.Split(text, "\n")
-
-	for _, para := range paragraphs {
-		if para == "" {
-			lines = append(lines, "")
+	// Check if we're at the bottom before updating content.
+	wasAtBottom := l.atBottom()
 
-			continue
-		}
+	contentWidth := l.width - 5 // border (2) + scrollbar (1) + padding (2)
 
-		for runewidth.StringWidth(para) > width {
-			breakAt := findBreakPoint(para, width)
-			lines = append(lines, para[:breakAt])
-			para = strings.TrimLeft(para[breakAt:], " ")
-		}
-		if para != "" {
-			lines = append(lines, para)
-		}
+	doc := l.content.Session(l.session.Messages, render.Options{
+		Width:        contentWidth,
+		Filter:       l.filter,
+		MaxDiffLines: collapsedDiffLines,
+		Markdown:     l.markdown,
+		Timestamps:   l.timestamps,
+		Now:          time.Now(),
+		State:        l.blockState,
+		Cache:        l.cache,
+	})
+	// Lines the new content shares with the old; none when another session was shown.
+	unchanged := min(l.cache.Unchanged(), len(l.lines))
+	l.lines = doc.Lines
+	l.msgStarts = doc.MsgStarts
+	l.blocks = l.blocks[:0]
+	for _, b := range doc.Blocks {
+		l.blocks = append(l.blocks, blockRef{key: blockKey{msg: b.Msg, block: b.Block}, start: b.Start, end: b.End})
 	}
+	l.findMatches(unchanged)
 
-	return lines
-}
-
-func findBreakPoint(text string, width int) int {
-	currentWidth := 0
-	bytePos := 0
-
-	for i, r := range text {
-		rw := runewidth.RuneWidth(r)
-		if currentWidth+rw > width {
-			bytePos = i
-
-			break
-		}
-		currentWidth += rw
-		bytePos = i + len(string(r))
-	}
+	// Only scroll to bottom if we were already at the bottom.
+	if wasAtBottom {
+		l.GotoBottom()
 
-	for i := bytePos; i > 0; i-- {
-		if text[i] == ' ' {
-			return i
-		}
+		return
 	}
-
-	return bytePos
+	l.applyContent()
 }
 
-func formatToolInput(input any, maxWidth int) []string {
-	if input == nil {
-		return nil
-	}
+// applyContent gives the lines around the visible ones to the viewport again, e.g. after the search highlights changed.
+func (l *LogViewport) applyContent() {
+	l.scrollTo(l.top())
+}
 
-	inputMap, ok := input.(map[string]any)
-	if !ok {
-		str := fmt.Sprintf("%v", input)
+// scrollTo scrolls so that the given content line is at the top of the viewport.
+// Only the visible lines and a margin of a few pages around them are given to the viewport,
+// styled and with search highlights, so that updating it takes the same time however long the transcript is.
+// The margin lets the viewport scroll by itself until ensureWindow moves the lines along.
+func (l *LogViewport) scrollTo(line int) {
+	height := max(1, l.viewport.Height)
+	line = max(0, min(line, len(l.lines)-height))
+	margin := windowMargin * height
+	start := max(0, line-margin)
+	end := min(len(l.lines), line+height+margin)
+	l.window = [2]int{start, end}
+	l.viewport.SetContent(strings.Join(l.highlightedLines(start, end), "\n"))
+	l.viewport.SetYOffset(line - start)
+}
 
-		return []string{truncateText(str, maxWidth)}
+// ensureWindow gives the viewport the lines around the visible ones again once it scrolled
+// within a page of the edge of the lines it has, unless that edge is the start or end of the content.
+func (l *LogViewport) ensureWindow() {
+	top := l.top()
+	height := max(1, l.viewport.Height)
+	if (l.window[0] > 0 && top-l.window[0] < height) ||
+		(l.window[1] < len(l.lines) && l.window[1]-(top+height) < height) {
+		l.scrollTo(top)
 	}
+}
 
-	keys := make([]string, 0, len(inputMap))
-	for key := range inputMap {
-		keys = append(keys, key)
-	}
-	sort.Strings(keys)
-
-	lines := make([]string, 0, len(keys))
-	for _, key := range keys {
-		value := inputMap[key]
-		var valueStr string
-		switch v := value.(type) {
-		case string:
-			if len(v) > 50 {
-				valueStr = v[:47] + "..."
-			} else {
-				valueStr = v
-			}
-			valueStr = strings.ReplaceAll(valueStr, "\n", "\\n")
-		default:
-			jsonBytes, err := json.Marshal(v)
-			if err != nil {
-				valueStr = fmt.Sprintf("%v", v)
-			} else {
-				valueStr = string(jsonBytes)
-			}
-			if len(valueStr) > 50 {
-				valueStr = valueStr[:47] + "..."
-			}
-		}
+// blockState returns the display state of a content block.
+func (l *LogViewport) blockState(msg, block int, b parser.ContentBlock) render.BlockState {
+	key := blockKey{msg: msg, block: block}
 
-		line := fmt.Sprintf("%s: %s", key, valueStr)
-		lines = append(lines, truncateText(line, maxWidth))
+	return render.BlockState{
+		Expanded: l.isExpanded(key, b),
+		Selected: l.cursor != nil && *l.cursor == key,
 	}
+}
 
-	return lines
+// Refresh updates the content from the current session.
+func (l *LogViewport) Refresh() {
+	l.updateContent()
 }
@@ -2,17 +2,23 @@ package components
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-runewidth"
 	"github.com/sters/cc-session-tailing/internal/session"
+	"github.com/sters/cc-session-tailing/internal/theme"
 )
 
 // TreeItem represents a flattened tree item for display.
+// A group header is an item without a session; it cannot be selected.
 type TreeItem struct {
 	Session  *session.Session
+	Node     *session.Node
+	Header   string // group name of a header item
+	Count    int    // number of root sessions under a header item
 	Depth    int
 	HasChild bool
 	IsLast   bool
@@ -26,8 +32,11 @@ type SessionTree struct {
 	width       int
 	height      int
 	focused     bool
-	offset      int             // scroll offset
-	highlighted map[string]bool // session IDs that are currently highlighted
+	offset      int               // scroll offset
+	highlighted map[string]bool   // session IDs that are currently highlighted
+	unread      map[string]int    // session ID -> number of unread messages
+	collapsed   map[string]bool   // session IDs whose children are hidden
+	groups      map[string]string // root session ID -> group header; nil shows no headers
 }
 
 // NewSessionTree creates a new session tree.
@@ -35,6 +44,8 @@ func NewSessionTree() *SessionTree {
 	return &SessionTree{
 		focused:     true,
 		highlighted: make(map[string]bool),
+		unread:      make(map[string]int),
+		collapsed:   make(map[string]bool),
 	}
 }
 
@@ -44,6 +55,13 @@ func (t *SessionTree) SetSize(width, height int) {
 	t.height = height
 }
 
+// SetGroups sets the group header of each root session, by session ID.
+// Root sessions of a group are listed together under its header, groups in order of their first session.
+// Nil removes the headers. The groups take effect on the next tree update.
+func (t *SessionTree) SetGroups(groups map[string]string) {
+	t.groups = groups
+}
+
 // SetSessionTree updates the tree from Node structure.
 func (t *SessionTree) SetSessionTree(nodes []*session.Node) {
 	t.setSessionTreeInternal(nodes, false)
@@ -55,11 +73,112 @@ func (t *SessionTree) SetSessionTreeSorted(nodes []*session.Node) {
 	t.setSessionTreeInternal(nodes, true)
 }
 
+// SetSessionTreeOrdered updates the tree from Node structure using a saved display order.
+// Sessions missing from order are placed first, keeping their incoming order.
+func (t *SessionTree) SetSessionTreeOrdered(nodes []*session.Node, order []string) {
+	rank := make(map[string]int, len(order))
+	for i, id := range order {
+		rank[id] = i + 1
+	}
+
+	t.setSessionTreeInternal(orderNodes(nodes, rank), true)
+}
+
+// orderNodes sorts nodes and their children by rank (0 = unknown, placed first).
+func orderNodes(nodes []*session.Node, rank map[string]int) []*session.Node {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return rank[nodes[i].Session.ID] < rank[nodes[j].Session.ID]
+	})
+	for _, n := range nodes {
+		n.Children = orderNodes(n.Children, rank)
+	}
+
+	return nodes
+}
+
+// Order returns the session IDs in current display order, including the children of collapsed sessions.
+func (t *SessionTree) Order() []string {
+	sessions := t.allSessions()
+	ids := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		ids = append(ids, s.ID)
+	}
+
+	return ids
+}
+
+// allSessions returns every session of the tree in display order, including hidden ones.
+func (t *SessionTree) allSessions() []*session.Session {
+	var sessions []*session.Session
+	var walk func(nodes []*session.Node)
+	walk = func(nodes []*session.Node) {
+		for _, n := range nodes {
+			sessions = append(sessions, n.Session)
+			walk(n.Children)
+		}
+	}
+	walk(t.nodes)
+
+	return sessions
+}
+
+// SelectSession moves the selection to the given session, expanding its collapsed ancestors.
+// Returns false if the session is not in the tree.
+func (t *SessionTree) SelectSession(sessionID string) bool {
+	if t.selectVisible(sessionID) {
+		return true
+	}
+
+	parents := make(map[string]string)
+	for _, s := range t.allSessions() {
+		parents[s.ID] = s.ParentID
+	}
+	if _, ok := parents[sessionID]; !ok {
+		return false
+	}
+	for id := parents[sessionID]; id != ""; id = parents[id] {
+		delete(t.collapsed, id)
+	}
+	t.reflatten()
+
+	return t.selectVisible(sessionID)
+}
+
+// selectVisible selects the given session if it is shown.
+func (t *SessionTree) selectVisible(sessionID string) bool {
+	for i, item := range t.items {
+		if item.Session != nil && item.Session.ID == sessionID {
+			t.selected = i
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// SelectedIndex returns the index of the selected item in display order.
+func (t *SessionTree) SelectedIndex() int {
+	return t.selected
+}
+
+// SelectAt selects the item shown on the given row, counted from the top border of the tree.
+// Returns false if no item is shown there.
+func (t *SessionTree) SelectAt(row int) bool {
+	idx := t.offset + row - 1
+	if row < 1 || row > t.height-4 || idx < 0 || idx >= len(t.items) || t.items[idx].Session == nil {
+		return false
+	}
+	t.selected = idx
+
+	return true
+}
+
 func (t *SessionTree) setSessionTreeInternal(nodes []*session.Node, forceSort bool) {
 	// Remember currently selected session ID to preserve focus.
 	var selectedSessionID string
-	if t.selected >= 0 && t.selected < len(t.items) {
-		selectedSessionID = t.items[t.selected].Session.ID
+	if selected := t.SelectedSession(); selected != nil {
+		selectedSessionID = selected.ID
 	}
 
 	// Preserve current display order if we already have nodes (unless forcing sort).
@@ -67,24 +186,92 @@ func (t *SessionTree) setSessionTreeInternal(nodes []*session.Node, forceSort bo
 		nodes = t.preserveOrder(nodes)
 	}
 
-	t.nodes = nodes
-	t.items = t.flattenTree(nodes, 0)
+	t.nodes = t.groupNodes(nodes)
+	t.items = t.flatten()
 
 	// Try to find the previously selected session.
-	if selectedSessionID != "" {
-		for i, item := range t.items {
-			if item.Session.ID == selectedSessionID {
-				t.selected = i
-
-				return
-			}
-		}
+	if selectedSessionID != "" && t.selectVisible(selectedSessionID) {
+		return
 	}
 
 	// Fall back to clamping selection if session not found.
 	if t.selected >= len(t.items) && len(t.items) > 0 {
 		t.selected = len(t.items) - 1
 	}
+	t.skipHeader()
+}
+
+// groupNodes moves the root sessions of each group together, groups in order of their first session.
+func (t *SessionTree) groupNodes(nodes []*session.Node) []*session.Node {
+	if t.groups == nil {
+		return nodes
+	}
+
+	rank := make(map[string]int)
+	for _, n := range nodes {
+		if _, ok := rank[t.groups[n.Session.ID]]; !ok {
+			rank[t.groups[n.Session.ID]] = len(rank)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return rank[t.groups[nodes[i].Session.ID]] < rank[t.groups[nodes[j].Session.ID]]
+	})
+
+	return nodes
+}
+
+// flatten converts the tree to the displayed items, with a header before each group.
+func (t *SessionTree) flatten() []TreeItem {
+	if t.groups == nil {
+		return t.flattenTree(t.nodes, 0)
+	}
+
+	items := make([]TreeItem, 0, len(t.nodes))
+	header := -1
+	for i, node := range t.nodes {
+		group := t.groups[node.Session.ID]
+		if header < 0 || group != items[header].Header {
+			header = len(items)
+			items = append(items, TreeItem{Header: group})
+		}
+		items[header].Count++
+		items = append(items, t.flattenTree(t.nodes[i:i+1], 0)...)
+	}
+
+	return items
+}
+
+// skipHeader moves the selection off a group header to the session below it.
+func (t *SessionTree) skipHeader() {
+	if t.selected >= 0 && t.selected < len(t.items)-1 && t.items[t.selected].Session == nil {
+		t.selected++
+	}
+}
+
+// reflatten rebuilds the displayed items after the expansion state changed.
+// When the selected session is hidden, its nearest shown ancestor is selected.
+func (t *SessionTree) reflatten() {
+	selected := t.SelectedSession()
+	t.items = t.flatten()
+	for s := selected; s != nil; {
+		if t.selectVisible(s.ID) {
+			return
+		}
+		s = t.findSession(s.ParentID)
+	}
+	t.selected = max(0, min(t.selected, len(t.items)-1))
+	t.skipHeader()
+}
+
+// findSession returns the session with the given ID anywhere in the tree, or nil.
+func (t *SessionTree) findSession(sessionID string) *session.Session {
+	for _, s := range t.allSessions() {
+		if s.ID == sessionID {
+			return s
+		}
+	}
+
+	return nil
 }
 
 // preserveOrder reorders nodes to match the current display order.
@@ -157,15 +344,18 @@ func (t *SessionTree) preserveChildOrder(oldChildren, newChildren []*session.Nod
 }
 
 // flattenTree converts the tree structure to a flat list for display.
+// Children of collapsed sessions are left out.
 func (t *SessionTree) flattenTree(nodes []*session.Node, depth int) []TreeItem {
 	items := make([]TreeItem, 0, len(nodes))
 
 	for i, node := range nodes {
 		isLast := i == len(nodes)-1
 		hasChild := len(node.Children) > 0
+		node.Expanded = !t.collapsed[node.Session.ID]
 
 		items = append(items, TreeItem{
 			Session:  node.Session,
+			Node:     node,
 			Depth:    depth,
 			HasChild: hasChild,
 			IsLast:   isLast,
@@ -180,6 +370,76 @@ func (t *SessionTree) flattenTree(nodes []*session.Node, depth int) []TreeItem {
 	return items
 }
 
+// SetExpanded expands or collapses the selected session.
+// Returns false if it has no children or already is in that state.
+func (t *SessionTree) SetExpanded(expanded bool) bool {
+	if !t.HasChildren() || t.IsExpanded() == expanded {
+		return false
+	}
+
+	id := t.items[t.selected].Session.ID
+	if expanded {
+		delete(t.collapsed, id)
+	} else {
+		t.collapsed[id] = true
+	}
+	t.reflatten()
+
+	return true
+}
+
+// ToggleExpanded expands the selected session if it is collapsed, and collapses it otherwise.
+func (t *SessionTree) ToggleExpanded() {
+	t.SetExpanded(!t.IsExpanded())
+}
+
+// IsExpanded returns whether the selected session shows its children.
+func (t *SessionTree) IsExpanded() bool {
+	if t.selected < 0 || t.selected >= len(t.items) {
+		return false
+	}
+
+	return t.items[t.selected].HasChild && t.items[t.selected].Node.Expanded
+}
+
+// SetAllExpanded expands or collapses every session with children.
+func (t *SessionTree) SetAllExpanded(expanded bool) {
+	t.collapsed = make(map[string]bool)
+	if !expanded {
+		var walk func(nodes []*session.Node)
+		walk = func(nodes []*session.Node) {
+			for _, n := range nodes {
+				if len(n.Children) > 0 {
+					t.collapsed[n.Session.ID] = true
+					walk(n.Children)
+				}
+			}
+		}
+		walk(t.nodes)
+	}
+	t.reflatten()
+}
+
+// Collapsed returns the IDs of the collapsed sessions, sorted.
+func (t *SessionTree) Collapsed() []string {
+	ids := make([]string, 0, len(t.collapsed))
+	for id := range t.collapsed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// SetCollapsed collapses the given sessions and expands all others.
+func (t *SessionTree) SetCollapsed(ids []string) {
+	t.collapsed = make(map[string]bool, len(ids))
+	for _, id := range ids {
+		t.collapsed[id] = true
+	}
+	t.reflatten()
+}
+
 // SetFocused sets the focus state.
 func (t *SessionTree) SetFocused(focused bool) {
 	t.focused = focused
@@ -210,20 +470,21 @@ func (t *SessionTree) Update(_ tea.Msg) tea.Cmd {
 
 // View renders the tree.
 func (t *SessionTree) View() string {
-	borderColor := lipgloss.Color("240")
+	c := theme.Colors()
+	borderColor := c.Border
 	if t.focused {
-		borderColor = lipgloss.Color("212")
+		borderColor = c.Accent
 	}
 
 	borderStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
+		Border(theme.BorderFor(t.focused)).
 		BorderForeground(borderColor).
 		Width(t.width - 2).
 		Height(t.height - 2)
 
 	if len(t.items) == 0 {
 		emptyStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
+			Foreground(c.Muted).
 			Italic(true)
 
 		return borderStyle.Render(emptyStyle.Render("No sessions"))
@@ -254,6 +515,10 @@ func (t *SessionTree) adjustScroll(visibleHeight int) {
 	// Ensure selected item is visible.
 	if t.selected < t.offset {
 		t.offset = t.selected
+		// Keep the header of the first group in view.
+		if t.offset > 0 && t.items[t.offset-1].Session == nil {
+			t.offset--
+		}
 	}
 	if t.selected >= t.offset+visibleHeight {
 		t.offset = t.selected - visibleHeight + 1
@@ -264,17 +529,39 @@ func (t *SessionTree) adjustScroll(visibleHeight int) {
 }
 
 func (t *SessionTree) renderItem(idx int) string {
+	c := theme.Colors()
+	g := theme.Symbols()
 	item := t.items[idx]
+	if item.Session == nil {
+		return t.renderHeader(item)
+	}
 	isSelected := idx == t.selected
 	isHighlighted := t.highlighted[item.Session.ID]
+	unreadCount := t.unread[item.Session.ID]
+	isUnread := unreadCount > 0
+
+	// A collapsed session shows the activity of the sessions it hides.
+	hidden := 0
+	if item.HasChild && !item.Node.Expanded {
+		var walk func(nodes []*session.Node)
+		walk = func(nodes []*session.Node) {
+			for _, n := range nodes {
+				hidden++
+				isHighlighted = isHighlighted || t.highlighted[n.Session.ID]
+				isUnread = isUnread || t.unread[n.Session.ID] > 0
+				walk(n.Children)
+			}
+		}
+		walk(item.Node.Children)
+	}
 
 	// Build prefix for tree structure.
 	prefix := strings.Repeat("  ", item.Depth)
 	if item.Depth > 0 {
 		if item.IsLast {
-			prefix = strings.Repeat("  ", item.Depth-1) + "└─"
+			prefix = strings.Repeat("  ", item.Depth-1) + g.LastBranch
 		} else {
-			prefix = strings.Repeat("  ", item.Depth-1) + "├─"
+			prefix = strings.Repeat("  ", item.Depth-1) + g.Branch
 		}
 	}
 
@@ -288,20 +575,40 @@ func (t *SessionTree) renderItem(idx int) string {
 		}
 	}
 
-	// Child indicator.
+	// Child indicator, with the number of hidden sessions when collapsed.
 	childIndicator := ""
-	if item.HasChild {
-		childIndicator = " ▶"
+	switch {
+	case hidden > 0:
+		childIndicator = fmt.Sprintf(" %s[%d]", g.Children, hidden)
+	case item.HasChild:
+		childIndicator = " " + g.Expanded
 	}
 
-	// Message count.
+	// Message count, with unread count when there are unread messages.
 	msgCount := len(item.Session.Messages)
 	countStr := fmt.Sprintf(" (%d)", msgCount)
+	if unreadCount > 0 {
+		countStr = fmt.Sprintf(" (%d +%d)", msgCount, unreadCount)
+	}
 
-	// Update indicator for highlighted sessions.
+	// Update indicator for highlighted or unread sessions.
 	updateIndicator := ""
-	if isHighlighted && !isSelected {
-		updateIndicator = " ●"
+	if (isHighlighted || isUnread) && !isSelected {
+		updateIndicator = " " + g.Activity
+	}
+
+	// Plain mode spells out the states that are otherwise shown only by color.
+	if theme.Plain() {
+		prefix = "  " + prefix
+		if isSelected {
+			prefix = "> " + prefix[2:]
+		}
+		switch {
+		case isHighlighted:
+			updateIndicator = " [active]"
+		case isUnread:
+			updateIndicator = " [unread]"
+		}
 	}
 
 	// Calculate available width.
@@ -321,47 +628,80 @@ func (t *SessionTree) renderItem(idx int) string {
 	// Apply styles.
 	if isSelected {
 		selectedStyle := lipgloss.NewStyle().
-			Background(lipgloss.Color("212")).
-			Foreground(lipgloss.Color("235")).
+			Background(c.Accent).
+			Foreground(c.OnAccent).
 			Bold(true).
 			Width(t.width - 4)
 
-		return selectedStyle.Render(line)
+		return selectedStyle.Render(line + updateIndicator)
 	}
 
 	if isHighlighted {
-		// Highlighted style - yellow/orange background flash effect.
+		// Highlighted style - background flash effect.
 		highlightStyle := lipgloss.NewStyle().
-			Background(lipgloss.Color("220")). // Yellow background
-			Foreground(lipgloss.Color("235")). // Dark text
+			Background(c.Highlight).
+			Foreground(c.OnHighlight).
 			Bold(true).
 			Width(t.width - 4)
 
 		return highlightStyle.Render(line + updateIndicator)
 	}
 
+	if isUnread {
+		// Unread style - activity the user has not viewed yet.
+		unreadStyle := lipgloss.NewStyle().
+			Foreground(c.Highlight).
+			Bold(true).
+			Width(t.width - 4)
+
+		return unreadStyle.Render(line + updateIndicator)
+	}
+
 	normalStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("252")).
+		Foreground(c.Text).
 		Width(t.width - 4)
 
 	if item.Session.IsSubagent {
-		normalStyle = normalStyle.Foreground(lipgloss.Color("243"))
+		normalStyle = normalStyle.Foreground(c.Subtle)
 	}
 
 	return normalStyle.Render(line)
 }
 
-// MoveUp moves selection up.
+// renderHeader renders a group header: its name and the number of sessions in it.
+func (t *SessionTree) renderHeader(item TreeItem) string {
+	g := theme.Symbols()
+	style := lipgloss.NewStyle().
+		Foreground(theme.Colors().Muted).
+		Bold(true)
+
+	width := t.width - 4
+	line := fmt.Sprintf("%s %s (%d) ", strings.Repeat(g.HLine, 2), item.Header, item.Count)
+	line = runewidth.Truncate(line, width, "...")
+	line += strings.Repeat(g.HLine, max(0, width-runewidth.StringWidth(line)))
+
+	return style.Render(line)
+}
+
+// MoveUp moves selection up, skipping group headers.
 func (t *SessionTree) MoveUp() {
-	if t.selected > 0 {
-		t.selected--
+	for i := t.selected - 1; i >= 0; i-- {
+		if t.items[i].Session != nil {
+			t.selected = i
+
+			return
+		}
 	}
 }
 
-// MoveDown moves selection down.
+// MoveDown moves selection down, skipping group headers.
 func (t *SessionTree) MoveDown() {
-	if t.selected < len(t.items)-1 {
-		t.selected++
+	for i := t.selected + 1; i < len(t.items); i++ {
+		if t.items[i].Session != nil {
+			t.selected = i
+
+			return
+		}
 	}
 }
 
@@ -391,10 +731,11 @@ func (t *SessionTree) MoveToChild() bool {
 	return false
 }
 
-// ResetSelection resets the selection to the first item.
+// ResetSelection resets the selection to the first session.
 func (t *SessionTree) ResetSelection() {
 	t.selected = 0
 	t.offset = 0
+	t.skipHeader()
 }
 
 // MoveToParent moves to the parent of the selected session.
@@ -448,6 +789,37 @@ func (t *SessionTree) ClearHighlighted() {
 	t.highlighted = make(map[string]bool)
 }
 
+// SetUnread sets the unread message counts by session ID.
+func (t *SessionTree) SetUnread(counts map[string]int) {
+	t.unread = counts
+}
+
+// SelectNextUnread moves the selection to the next session with unread messages,
+// wrapping around to the top. Sessions hidden in collapsed sessions are included and revealed.
+// Returns false if no other session has unread messages.
+func (t *SessionTree) SelectNextUnread() bool {
+	sessions := t.allSessions()
+	current := 0
+	if selected := t.SelectedSession(); selected != nil {
+		for i, s := range sessions {
+			if s.ID == selected.ID {
+				current = i
+
+				break
+			}
+		}
+	}
+
+	for i := 1; i < len(sessions); i++ {
+		s := sessions[(current+i)%len(sessions)]
+		if t.unread[s.ID] > 0 {
+			return t.SelectSession(s.ID)
+		}
+	}
+
+	return false
+}
+
 // HasHighlighted returns whether there are any highlighted sessions.
 func (t *SessionTree) HasHighlighted() bool {
 	return len(t.highlighted) > 0
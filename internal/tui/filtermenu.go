@@ -0,0 +1,196 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sters/cc-session-tailing/internal/filter"
+	"github.com/sters/cc-session-tailing/internal/session"
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// FilterMenu is an overlay for toggling which block kinds and tools a view shows.
+type FilterMenu struct {
+	active bool
+	filter *filter.Filter
+	tools  []string // tool names listed below the block kinds
+	cursor int
+	keys   *KeyMap
+}
+
+// NewFilterMenu creates a filter menu.
+func NewFilterMenu() *FilterMenu {
+	return &FilterMenu{keys: DefaultKeyMap()}
+}
+
+// Open shows the menu for a filter, listing the tools used in the given sessions.
+func (fm *FilterMenu) Open(f *filter.Filter, sessions []*session.Session) {
+	fm.active = true
+	fm.filter = f
+	fm.tools = toolNamesOf(sessions, f.HiddenTools())
+	fm.cursor = 0
+}
+
+// Active returns whether the menu is shown.
+func (fm *FilterMenu) Active() bool {
+	return fm.active
+}
+
+// Update handles a key while the menu is shown.
+// It returns true when the filter changed.
+func (fm *FilterMenu) Update(msg tea.KeyMsg) bool {
+	items := len(filter.Kinds) + len(fm.tools)
+
+	switch k := fm.keys; {
+	case key.Matches(msg, k.Back, k.Quit, k.Filter):
+		fm.active = false
+	case key.Matches(msg, k.Down):
+		fm.cursor = min(fm.cursor+1, items-1)
+	case key.Matches(msg, k.Up):
+		fm.cursor = max(fm.cursor-1, 0)
+	case key.Matches(msg, k.MenuToggle):
+		fm.toggle(fm.cursor)
+
+		return true
+	case key.Matches(msg, k.MenuOnly):
+		fm.only(fm.cursor)
+
+		return true
+	case key.Matches(msg, k.MenuShowAll):
+		fm.filter.Reset()
+
+		return true
+	}
+
+	return false
+}
+
+// toggle shows or hides the item at index i.
+func (fm *FilterMenu) toggle(i int) {
+	if i < len(filter.Kinds) {
+		fm.filter.ToggleKind(filter.Kinds[i])
+
+		return
+	}
+	fm.filter.ToggleTool(fm.tools[i-len(filter.Kinds)])
+}
+
+// only shows just the item at index i: a block kind hides all other kinds,
+// a tool hides all other tools.
+func (fm *FilterMenu) only(i int) {
+	if i < len(filter.Kinds) {
+		for _, k := range filter.Kinds {
+			if fm.filter.KindHidden(k) != (k != filter.Kinds[i]) {
+				fm.filter.ToggleKind(k)
+			}
+		}
+
+		return
+	}
+
+	name := fm.tools[i-len(filter.Kinds)]
+	for _, tool := range fm.tools {
+		if fm.filter.ToolHidden(tool) != (tool != name) {
+			fm.filter.ToggleTool(tool)
+		}
+	}
+	if fm.filter.KindHidden(filter.KindToolUse) {
+		fm.filter.ToggleKind(filter.KindToolUse)
+	}
+}
+
+// View renders the menu centered in the given area.
+func (fm *FilterMenu) View(width, height int) string {
+	c := theme.Colors()
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(c.Accent)
+	labelStyle := lipgloss.NewStyle().Foreground(c.Muted)
+	cursorStyle := lipgloss.NewStyle().Background(c.Selection).Bold(true)
+
+	lines := []string{titleStyle.Render("Show blocks"), ""}
+
+	item := func(i int, shown bool, name string) string {
+		mark := "[ ]"
+		if shown {
+			mark = "[x]"
+		}
+		line := mark + " " + name
+		if theme.Plain() {
+			// The cursor is shown by a marker instead of the background color.
+			line = "  " + line
+			if i == fm.cursor {
+				line = "> " + line[2:]
+			}
+		}
+		if i == fm.cursor {
+			return cursorStyle.Render(line)
+		}
+
+		return line
+	}
+
+	for i, k := range filter.Kinds {
+		lines = append(lines, item(i, !fm.filter.KindHidden(k), fmt.Sprintf("%-7s", k))+labelStyle.Render(fmt.Sprintf(" (%d)", i+1)))
+	}
+	if len(fm.tools) > 0 {
+		lines = append(lines, "", titleStyle.Render("Tools"))
+	}
+
+	// Keep the cursor visible when there are many tools.
+	first := 0
+	visible := max(1, height-len(filter.Kinds)-10)
+	toolCursor := fm.cursor - len(filter.Kinds)
+	if toolCursor >= visible {
+		first = toolCursor - visible + 1
+	}
+	for j := first; j < len(fm.tools) && j < first+visible; j++ {
+		lines = append(lines, item(len(filter.Kinds)+j, !fm.filter.ToolHidden(fm.tools[j]), fm.tools[j]))
+	}
+
+	k := fm.keys
+	lines = append(lines, "", labelStyle.Render(helpLine(
+		help("toggle", k.MenuToggle),
+		help("only", k.MenuOnly),
+		help("show all", k.MenuShowAll),
+		help("close", k.Back),
+	)))
+
+	box := lipgloss.NewStyle().
+		Border(theme.Symbols().Border).
+		BorderForeground(c.Accent).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, box)
+}
+
+// toolNamesOf returns the sorted tool names used in the sessions, plus extra names.
+func toolNamesOf(sessions []*session.Session, extra []string) []string {
+	seen := make(map[string]bool)
+	for _, name := range extra {
+		seen[name] = true
+	}
+	for _, sess := range sessions {
+		if sess == nil {
+			continue
+		}
+		for _, msg := range sess.Messages {
+			for _, block := range msg.Message.Content {
+				if block.Type == "tool_use" && block.Name != "" {
+					seen[block.Name] = true
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,352 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/sters/cc-session-tailing/internal/search"
+	"github.com/sters/cc-session-tailing/internal/session"
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// globalSearchLimit is the maximum number of results of a cross-session search.
+const globalSearchLimit = 500
+
+// searchScope selects which sessions a cross-session search reads.
+type searchScope int
+
+const (
+	// scopeLoaded searches the sessions held by the session manager.
+	scopeLoaded searchScope = iota
+	// scopeProject also searches the session files of the project that the manager does not hold.
+	scopeProject
+	// scopeAll also searches the session files of every other project.
+	scopeAll
+)
+
+// searchScopeNames holds the labels of the search scopes, indexed by searchScope.
+var searchScopeNames = []string{"loaded sessions", "project files", "all projects"} //nolint:gochecknoglobals // package-level config
+
+// next returns the scope that follows s when cycling.
+func (s searchScope) next() searchScope {
+	return (s + 1) % searchScope(len(searchScopeNames))
+}
+
+// String returns the scope label.
+func (s searchScope) String() string {
+	return searchScopeNames[s]
+}
+
+// GlobalSearchResultsMsg carries the results of a cross-session search.
+type GlobalSearchResultsMsg struct {
+	Query   search.Query
+	Scope   searchScope // sessions that were searched
+	Results []search.Result
+	Err     error
+}
+
+// GlobalSearchOpenMsg is sent when a search result is chosen.
+type GlobalSearchOpenMsg struct {
+	Result search.Result
+}
+
+// GlobalSearch is an overlay that searches message text across all sessions.
+type GlobalSearch struct {
+	manager     *session.Manager
+	projectPath string // watched project directory; its parent holds the other projects
+	active      bool
+	input       string
+	regex       bool
+	scope       searchScope
+	running     bool
+	searched    *search.Query // query of the shown results (nil = not searched yet)
+	searchedIn  searchScope   // scope of the shown results
+	results     []search.Result
+	err         error
+	selected    int
+	offset      int
+	width       int
+	height      int
+	keys        *KeyMap
+}
+
+// NewGlobalSearch creates a cross-session search overlay.
+func NewGlobalSearch(manager *session.Manager, projectPath string) *GlobalSearch {
+	return &GlobalSearch{
+		manager:     manager,
+		projectPath: projectPath,
+		keys:        DefaultKeyMap(),
+	}
+}
+
+// Open shows the overlay, keeping the previous query and results.
+func (g *GlobalSearch) Open() {
+	g.active = true
+}
+
+// Close hides the overlay.
+func (g *GlobalSearch) Close() {
+	g.active = false
+}
+
+// Active returns whether the overlay is shown.
+func (g *GlobalSearch) Active() bool {
+	return g.active
+}
+
+// SetSize sets the dimensions of the overlay.
+func (g *GlobalSearch) SetSize(width, height int) {
+	g.width = width
+	g.height = height
+}
+
+// query returns the query described by the current input and toggles.
+func (g *GlobalSearch) query() search.Query {
+	return search.Query{Pattern: g.input, Regex: g.regex}
+}
+
+// Update handles a key while the overlay is shown.
+func (g *GlobalSearch) Update(msg tea.KeyMsg) tea.Cmd {
+	switch k := g.keys; {
+	case key.Matches(msg, k.Back):
+		g.Close()
+
+		return nil
+	case key.Matches(msg, k.Open):
+		// Open the selected result if it belongs to the current query, otherwise search.
+		if g.searched != nil && *g.searched == g.query() && g.searchedIn == g.scope && !g.running {
+			if g.selected < len(g.results) {
+				result := g.results[g.selected]
+				g.Close()
+
+				return func() tea.Msg { return GlobalSearchOpenMsg{Result: result} }
+			}
+
+			return nil
+		}
+
+		return g.run()
+	case key.Matches(msg, k.SearchUp):
+		g.moveSelection(-1)
+	case key.Matches(msg, k.SearchDown):
+		g.moveSelection(1)
+	case key.Matches(msg, k.SearchPageUp):
+		g.moveSelection(-g.listHeight())
+	case key.Matches(msg, k.SearchPageDown):
+		g.moveSelection(g.listHeight())
+	case key.Matches(msg, k.SearchRegex):
+		g.regex = !g.regex
+	case key.Matches(msg, k.SearchAll):
+		g.scope = g.scope.next()
+	case key.Matches(msg, k.SearchClear):
+		g.input = ""
+	case msg.Type == tea.KeyBackspace:
+		if g.input != "" {
+			runes := []rune(g.input)
+			g.input = string(runes[:len(runes)-1])
+		}
+	case msg.Type == tea.KeySpace:
+		g.input += " "
+	case msg.Type == tea.KeyRunes:
+		g.input += string(msg.Runes)
+	}
+
+	return nil
+}
+
+// run starts a search in the background.
+// Loaded sessions are snapshotted here; files are read by the command.
+func (g *GlobalSearch) run() tea.Cmd {
+	q := g.query()
+	if strings.TrimSpace(q.Pattern) == "" {
+		return nil
+	}
+
+	matcher, err := search.Compile(q)
+	if err != nil {
+		g.searched = &q
+		g.searchedIn = g.scope
+		g.results = nil
+		g.err = err
+
+		return nil
+	}
+
+	sessions := g.manager.GetAllSessions()
+	targets := make([]search.Target, 0, len(sessions))
+	loaded := make(map[string]bool, len(sessions))
+	for _, sess := range sessions {
+		targets = append(targets, search.Target{SessionID: sess.ID, Messages: sess.Messages})
+		loaded[sess.Path] = true
+	}
+
+	scope := g.scope
+	root := g.projectPath
+	if scope == scopeAll {
+		root = filepath.Dir(g.projectPath)
+	}
+
+	g.running = true
+
+	return func() tea.Msg {
+		results := search.Sessions(targets, matcher, globalSearchLimit)
+		if scope == scopeLoaded || len(results) >= globalSearchLimit {
+			return GlobalSearchResultsMsg{Query: q, Scope: scope, Results: results}
+		}
+
+		skip := func(path string) bool { return loaded[path] }
+		fileResults, err := search.Files(root, skip, matcher, globalSearchLimit-len(results))
+
+		return GlobalSearchResultsMsg{Query: q, Scope: scope, Results: append(results, fileResults...), Err: err}
+	}
+}
+
+// SetResults shows the results of a finished search.
+func (g *GlobalSearch) SetResults(msg GlobalSearchResultsMsg) {
+	g.running = false
+	g.searched = &msg.Query
+	g.searchedIn = msg.Scope
+	g.results = msg.Results
+	g.err = msg.Err
+	g.selected = 0
+	g.offset = 0
+}
+
+func (g *GlobalSearch) moveSelection(delta int) {
+	if len(g.results) == 0 {
+		return
+	}
+
+	g.selected = max(0, min(g.selected+delta, len(g.results)-1))
+
+	height := g.listHeight()
+	if g.selected < g.offset {
+		g.offset = g.selected
+	}
+	if g.selected >= g.offset+height {
+		g.offset = g.selected - height + 1
+	}
+}
+
+// listHeight returns the number of result rows that fit on screen.
+func (g *GlobalSearch) listHeight() int {
+	// Border (2), input, status, separator and help line.
+	return max(1, g.height-6)
+}
+
+// View renders the overlay.
+func (g *GlobalSearch) View() string {
+	c := theme.Colors()
+	glyphs := theme.Symbols()
+	k := g.keys
+	inputStyle := lipgloss.NewStyle().Foreground(c.Text).Bold(true)
+	labelStyle := lipgloss.NewStyle().Foreground(c.Muted)
+	sessionStyle := lipgloss.NewStyle().Foreground(c.User).Bold(true)
+	selectedStyle := lipgloss.NewStyle().Background(c.Selection)
+	errorStyle := lipgloss.NewStyle().Foreground(c.Error)
+
+	innerWidth := max(1, g.width-4)
+
+	mode := "literal"
+	if g.regex {
+		mode = "regex"
+	}
+	lines := []string{
+		inputStyle.Render("Search all sessions: "+g.input+"_") +
+			labelStyle.Render(fmt.Sprintf("  [%s] [%s]", mode, g.scope)),
+	}
+
+	switch {
+	case g.running:
+		lines = append(lines, labelStyle.Render("Searching..."))
+	case g.err != nil:
+		lines = append(lines, errorStyle.Render(truncateLine(g.err.Error(), innerWidth)))
+	case g.searched == nil:
+		lines = append(lines, labelStyle.Render("Enter: search"))
+	case len(g.results) == 0:
+		lines = append(lines, labelStyle.Render("No matches"))
+	case len(g.results) >= globalSearchLimit:
+		lines = append(lines, labelStyle.Render(fmt.Sprintf("%d+ results (showing first %d)", globalSearchLimit, globalSearchLimit)))
+	default:
+		lines = append(lines, labelStyle.Render(fmt.Sprintf("%d results", len(g.results))))
+	}
+	lines = append(lines, labelStyle.Render(strings.Repeat(glyphs.HLine, innerWidth)))
+
+	end := min(len(g.results), g.offset+g.listHeight())
+	for i := g.offset; i < end; i++ {
+		r := g.results[i]
+
+		// Results are grouped by session; the ID is shown on the first row of each group.
+		id := ""
+		if i == g.offset || g.results[i-1].SessionID != r.SessionID {
+			id = r.SessionID
+		}
+		idWidth := min(30, innerWidth/3)
+		if theme.Plain() && i == g.selected {
+			// The selection is shown by a marker instead of the background color.
+			id = "> " + id
+		}
+		id = runewidth.FillRight(runewidth.Truncate(id, idWidth, glyphs.Ellipsis), idWidth)
+
+		meta := fmt.Sprintf(" #%-4d ", r.MessageIndex)
+		if !r.Timestamp.IsZero() {
+			meta += r.Timestamp.Local().Format("2006-01-02 15:04:05") + " "
+		}
+
+		snippetWidth := max(0, innerWidth-idWidth-runewidth.StringWidth(meta))
+		snippet := truncateLine(snippetText(r), snippetWidth)
+		row := sessionStyle.Render(id) + labelStyle.Render(meta) + snippet
+		if i == g.selected {
+			row = selectedStyle.Width(innerWidth).Render(id + meta + snippet)
+		}
+		lines = append(lines, row)
+	}
+
+	for len(lines) < g.listHeight()+3 {
+		lines = append(lines, "")
+	}
+
+	box := lipgloss.NewStyle().
+		Border(glyphs.Border).
+		BorderForeground(c.Accent).
+		Padding(0, 1).
+		Width(g.width - 2).
+		Render(strings.Join(lines, "\n"))
+
+	help := lipgloss.NewStyle().
+		Foreground(c.Muted).
+		Padding(0, 1).
+		Render(helpLine(
+			help("search / open", k.Open),
+			help("select", k.SearchUp, k.SearchDown),
+			help("regex", k.SearchRegex),
+			help("scope", k.SearchAll),
+			help("clear", k.SearchClear),
+			help("close", k.Back),
+		))
+
+	return lipgloss.JoinVertical(lipgloss.Left, box, help)
+}
+
+// snippetText returns the snippet of a result with an ellipsis where text was left out.
+func snippetText(r search.Result) string {
+	text := r.Snippet
+	if r.CutBefore {
+		text = theme.Symbols().Ellipsis + text
+	}
+	if r.CutAfter {
+		text += theme.Symbols().Ellipsis
+	}
+
+	return text
+}
+
+// truncateLine truncates text to the given display width.
+func truncateLine(text string, width int) string {
+	return runewidth.Truncate(text, width, theme.Symbols().Ellipsis)
+}
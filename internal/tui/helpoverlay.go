@@ -0,0 +1,313 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/sters/cc-session-tailing/internal/render"
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// helpOverlayWidth is the widest the help overlay gets.
+const helpOverlayWidth = 96
+
+// helpKeyWidth is the widest the key column of the help overlay gets.
+const helpKeyWidth = 36
+
+// HelpOverlay lists every key binding by context and explains the colors and glyphs.
+type HelpOverlay struct {
+	active bool
+	offset int // first content line shown
+	keys   *KeyMap
+}
+
+// helpGroup is a titled list of help items.
+type helpGroup struct {
+	title string
+	items []helpItem
+}
+
+// legendItem explains a colored sample or glyph.
+type legendItem struct {
+	sample string // rendered sample
+	desc   string
+}
+
+// NewHelpOverlay creates a help overlay.
+func NewHelpOverlay() *HelpOverlay {
+	return &HelpOverlay{keys: DefaultKeyMap()}
+}
+
+// Open shows the overlay from the top.
+func (h *HelpOverlay) Open() {
+	h.active = true
+	h.offset = 0
+}
+
+// Active returns whether the overlay is shown.
+func (h *HelpOverlay) Active() bool {
+	return h.active
+}
+
+// Update handles a key while the overlay is shown; height is the screen height.
+func (h *HelpOverlay) Update(msg tea.KeyMsg, height int) {
+	page := max(1, helpBodyHeight(height)-1)
+
+	switch k := h.keys; {
+	case key.Matches(msg, k.Back, k.Help, k.Quit):
+		h.active = false
+	case key.Matches(msg, k.Down):
+		h.offset++
+	case key.Matches(msg, k.Up):
+		h.offset--
+	case key.Matches(msg, k.PageDown):
+		h.offset += page
+	case key.Matches(msg, k.PageUp):
+		h.offset -= page
+	case key.Matches(msg, k.HalfPageDown):
+		h.offset += max(1, page/2)
+	case key.Matches(msg, k.HalfPageUp):
+		h.offset -= max(1, page/2)
+	case key.Matches(msg, k.Top):
+		h.offset = 0
+	case key.Matches(msg, k.Bottom):
+		h.offset = 1 << 30
+	}
+	// The offset is clamped to the content when rendering.
+	h.offset = max(0, h.offset)
+}
+
+// Scroll scrolls the overlay by delta lines.
+func (h *HelpOverlay) Scroll(delta int) {
+	h.offset = max(0, h.offset+delta)
+}
+
+// helpBodyHeight returns the number of content lines that fit on a screen of the given height.
+func helpBodyHeight(height int) int {
+	// Border (2), title, blank line and footer.
+	return max(1, height-5)
+}
+
+// View renders the overlay centered in the given area.
+func (h *HelpOverlay) View(width, height int) string {
+	c := theme.Colors()
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(c.Accent)
+	labelStyle := lipgloss.NewStyle().Foreground(c.Muted)
+
+	innerWidth := max(20, min(width-4, helpOverlayWidth))
+	content := h.lines(innerWidth)
+
+	body := helpBodyHeight(height)
+	h.offset = max(0, min(h.offset, len(content)-body))
+	end := min(len(content), h.offset+body)
+	shown := content[h.offset:end]
+	for len(shown) < body && len(content) > body {
+		shown = append(shown, "")
+	}
+
+	position := ""
+	if len(content) > body {
+		position = fmt.Sprintf(" (%d-%d of %d)", h.offset+1, end, len(content))
+	}
+	k := h.keys
+	footer := helpLine(
+		help("scroll", k.Down, k.Up),
+		help("page", k.PageDown, k.PageUp),
+		help("close", k.Back, k.Help),
+	) + position
+
+	lines := append([]string{titleStyle.Render("Help"), ""}, shown...)
+	lines = append(lines, labelStyle.Render(runewidth.Truncate(footer, innerWidth, theme.Symbols().Ellipsis)))
+
+	box := lipgloss.NewStyle().
+		Border(theme.Symbols().Border).
+		BorderForeground(c.Accent).
+		Padding(0, 1).
+		Width(innerWidth + 2).
+		Render(strings.Join(lines, "\n"))
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, box)
+}
+
+// lines returns the content of the overlay: the key bindings by context, then the legend.
+func (h *HelpOverlay) lines(width int) []string {
+	c := theme.Colors()
+	headingStyle := lipgloss.NewStyle().Bold(true).Foreground(c.Info)
+	keyStyle := lipgloss.NewStyle().Foreground(c.Strong)
+
+	groups := h.groups()
+	keyWidth := 0
+	for _, group := range groups {
+		for _, item := range group.items {
+			keyWidth = max(keyWidth, runewidth.StringWidth(itemKeys(item)))
+		}
+	}
+	keyWidth = min(keyWidth, helpKeyWidth, width/2)
+	descWidth := max(1, width-keyWidth-2)
+
+	var lines []string
+	for _, group := range groups {
+		var rows []string
+		for _, item := range group.items {
+			keys := itemKeys(item)
+			if keys == "" {
+				continue
+			}
+			keys = runewidth.FillRight(runewidth.Truncate(keys, keyWidth, theme.Symbols().Ellipsis), keyWidth)
+			rows = append(rows, keyStyle.Render(keys)+"  "+runewidth.Truncate(item.desc, descWidth, theme.Symbols().Ellipsis))
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		lines = append(lines, headingStyle.Render(group.title))
+		lines = append(lines, rows...)
+		lines = append(lines, "")
+	}
+
+	legend := legendItems()
+	sampleWidth := 0
+	for _, item := range legend {
+		sampleWidth = max(sampleWidth, lipgloss.Width(item.sample))
+	}
+	lines = append(lines, headingStyle.Render("Colors and glyphs"))
+	for _, item := range legend {
+		pad := strings.Repeat(" ", sampleWidth-lipgloss.Width(item.sample))
+		lines = append(lines, item.sample+pad+"  "+runewidth.Truncate(item.desc, max(1, width-sampleWidth-2), theme.Symbols().Ellipsis))
+	}
+
+	return lines
+}
+
+// groups returns the help items of each context, taken from the key bindings.
+func (h *HelpOverlay) groups() []helpGroup {
+	k := h.keys
+
+	return []helpGroup{
+		{"Global", []helpItem{
+			help("Show this help", k.Help),
+			help("Jump to a session or run a command", k.Palette),
+			help("Quit", k.Quit),
+			help("Switch between tree and panel mode", k.ToggleView),
+			help("Search all sessions", k.Search),
+			help("Open the filter menu", k.Filter),
+			help("Show/hide user, text, thinking, tool calls, tool results", k.FilterKind),
+			help("Show all blocks", k.FilterReset),
+			help("Toggle Markdown rendering", k.Markdown),
+			help("Cycle timestamps: off, time of day, age", k.Timestamps),
+		}},
+		{"Tree focus", []helpItem{
+			help("Select the next/previous session", k.Down, k.Up),
+			help("Focus the log of the selected session", k.Open),
+			help("Expand the selected session, or select its first subagent", k.Expand),
+			help("Collapse the selected session, or select its parent", k.Collapse),
+			help("Expand/collapse the selected session", k.ToggleNode),
+			help("Collapse/expand all sessions", k.CollapseAll, k.ExpandAll),
+			help("Jump to the next session with unread messages", k.NextUnread),
+			help("Follow the most active session; selecting by hand pauses it", k.Follow),
+			help("Sort sessions again (by last update in arrival order)", k.SortByTime),
+			help("Cycle the sort order: arrival, activity, created, title, messages, tokens, status", k.SortBy),
+			help("Cycle the grouping: none, day, branch, working directory", k.GroupBy),
+			help("Expand/collapse all thinking blocks", k.ExpandThinking),
+			help("Expand/collapse all tool inputs and results", k.ExpandTools),
+			help("Search the log", k.LogSearch),
+		}},
+		{"Log focus", []helpItem{
+			help("Scroll down/up", k.Down, k.Up),
+			help("Scroll a page down/up", k.PageDown, k.PageUp),
+			help("Scroll half a page down/up", k.HalfPageDown, k.HalfPageUp),
+			help("Move the block cursor to the next/previous block", k.NextBlock, k.PrevBlock),
+			help("Expand/collapse the block under the cursor", k.Open),
+			help("Expand/collapse all thinking blocks", k.ExpandThinking),
+			help("Expand/collapse all tool inputs and results", k.ExpandTools),
+			help("Search the log", k.LogSearch),
+			help("Jump to the next/previous match", k.NextMatch, k.PrevMatch),
+			help("Toggle fullscreen log", k.Fullscreen),
+			help("Clear the search or cursor, then focus the tree", k.Back),
+		}},
+		{"Panel mode", []helpItem{
+			help("Focus the next/previous panel", k.NextPanel, k.PrevPanel),
+			help("Scroll the focused panel down/up", k.Down, k.Up),
+			help("Scroll a page down/up", k.PageDown, k.PageUp),
+			help("Scroll half a page down/up", k.HalfPageDown, k.HalfPageUp),
+			help("Jump to the top / bottom (bottom follows new messages)", k.Top, k.Bottom),
+			help("Cycle the panel count", k.CyclePanels),
+			help("Add/remove a panel", k.MorePanels, k.FewerPanels),
+			help("Cycle the layout", k.Layout),
+			help("Zoom the focused panel", k.Zoom),
+			help("Pin/unpin the focused panel's session", k.Pin),
+			help("Swap the focused panel with its left/right neighbor", k.SwapLeft, k.SwapRight),
+		}},
+	}
+}
+
+// itemKeys returns every key of a help item's enabled bindings, e.g. "j down k up".
+func itemKeys(item helpItem) string {
+	var keys []string
+	for _, b := range item.bindings {
+		if !b.Enabled() {
+			continue
+		}
+		if len(b.Keys()) > 2 && isDigits(b.Keys()) {
+			keys = append(keys, keyLabel(b.Keys()))
+
+			continue
+		}
+		for _, k := range b.Keys() {
+			keys = append(keys, keyLabel([]string{k}))
+		}
+	}
+
+	return strings.Join(keys, " ")
+}
+
+// legendItems returns samples of the colors and glyphs the UI uses, in the current theme.
+func legendItems() []legendItem {
+	c := theme.Colors()
+	g := theme.Symbols()
+	plain := theme.Plain()
+	content := render.NewStyles()
+
+	style := func(fg lipgloss.Color) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(fg)
+	}
+	pick := func(normal, plainText string) string {
+		if plain {
+			return plainText
+		}
+
+		return normal
+	}
+
+	return []legendItem{
+		{lipgloss.NewStyle().Background(c.Accent).Foreground(c.OnAccent).Render(pick("session", "> session")), "Selected session"},
+		{lipgloss.NewStyle().Background(c.Highlight).Foreground(c.OnHighlight).Render(pick("session "+g.Activity, "session [active]")), "Session that just received messages"},
+		{style(c.Highlight).Render(pick("session (37 +5) "+g.Activity, "session (37 +5) [unread]")), "Messages you have not seen yet (total +unread)"},
+		{style(c.Subtle).Render(g.LastBranch + "agent-1"), "Subagent of the session above"},
+		{"session " + g.Expanded, "Session with subagents"},
+		{"session " + g.Children + "[3]", "Collapsed session hiding 3 subagents"},
+		{style(c.Accent).Render(pick(strings.Repeat(g.HLine, 7), strings.Repeat("=", 7))), "Border of the focused pane or panel"},
+		{style(c.Accent).Render(g.ScrollThumb) + style(c.Border).Render(g.ScrollTrack), "Scrollbar thumb and track"},
+		{content.User.Render("[USER]"), "User prompt"},
+		{content.Label.Render("[TEXT]"), "Assistant text"},
+		{content.Label.Render("[THINK]") + " " + content.Think.Render("..."), "Assistant thinking"},
+		{content.Label.Render("[TOOL]") + " " + content.Tool.Render("Bash"), "Tool call"},
+		{content.Label.Render("[RESULT]"), "Tool result"},
+		{content.Cursor.Render(pick("[TEXT]", "> TEXT")), "Block under the cursor"},
+		{searchSample(c.Highlight, pick("match", "[match]")) + " " + searchSample(c.HighlightStrong, pick("match", "[[match]]")), "Search match / current match"},
+		{content.Tools.Diff.Added.Render("+add") + " " + content.Tools.Diff.Removed.Render("-del"), "Added / removed lines"},
+		{content.Marker.Render(strings.Repeat(g.HLine, 2) + " idle 12m"), "Pause between messages"},
+		{content.Marker.Render(g.End + " turn 3m20s"), "Duration of a turn"},
+		{"[PIN] [SUB] [ZOOM]", "Pinned panel / subagent / zoomed panel"},
+		{"md time age", "Log header: Markdown on / times of day / ages"},
+		{"+think +io -text", "Log header: expanded blocks / hidden block kinds"},
+	}
+}
+
+// searchSample renders a search match sample on a highlight color.
+func searchSample(bg lipgloss.Color, text string) string {
+	return lipgloss.NewStyle().Background(bg).Foreground(theme.Colors().OnHighlight).Render(text)
+}
@@ -0,0 +1,417 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+// KeyMap holds the key bindings of all views.
+// ctrl+c always quits, whatever the bindings say.
+type KeyMap struct {
+	// Global.
+	Quit        key.Binding
+	ToggleView  key.Binding
+	Search      key.Binding
+	Filter      key.Binding
+	FilterKind  key.Binding // the n-th key toggles the n-th block kind
+	FilterReset key.Binding
+	Markdown    key.Binding
+	Timestamps  key.Binding
+	Help        key.Binding
+	Palette     key.Binding
+
+	// Navigation, shared by panels, the tree and the log viewport.
+	Up           key.Binding
+	Down         key.Binding
+	PageUp       key.Binding
+	PageDown     key.Binding
+	HalfPageUp   key.Binding
+	HalfPageDown key.Binding
+	Top          key.Binding
+	Bottom       key.Binding
+
+	// Panel mode.
+	CyclePanels key.Binding
+	MorePanels  key.Binding
+	FewerPanels key.Binding
+	Layout      key.Binding
+	Zoom        key.Binding
+	NextPanel   key.Binding
+	PrevPanel   key.Binding
+	Pin         key.Binding
+	SwapLeft    key.Binding
+	SwapRight   key.Binding
+
+	// Tree mode.
+	Open           key.Binding
+	Back           key.Binding
+	NextBlock      key.Binding
+	PrevBlock      key.Binding
+	ExpandThinking key.Binding
+	ExpandTools    key.Binding
+	LogSearch      key.Binding
+	NextMatch      key.Binding
+	PrevMatch      key.Binding
+	Fullscreen     key.Binding
+	SortByTime     key.Binding
+	SortBy         key.Binding // cycle the sort order of the tree
+	GroupBy        key.Binding // cycle the grouping of the tree
+	NextUnread     key.Binding
+	Follow         key.Binding // toggle following the most active session
+	Collapse       key.Binding // collapse the selected session, or select its parent
+	Expand         key.Binding // expand the selected session, or select its first child
+	ToggleNode     key.Binding
+	CollapseAll    key.Binding
+	ExpandAll      key.Binding
+
+	// Filter menu.
+	MenuToggle  key.Binding
+	MenuOnly    key.Binding
+	MenuShowAll key.Binding
+
+	// Cross-session search overlay, where letters are typed into the query.
+	SearchUp       key.Binding
+	SearchDown     key.Binding
+	SearchPageUp   key.Binding
+	SearchPageDown key.Binding
+	SearchRegex    key.Binding
+	SearchAll      key.Binding
+	SearchClear    key.Binding
+}
+
+// namedBinding is a binding with the name used for it in the config file.
+type namedBinding struct {
+	name    string
+	binding *key.Binding
+}
+
+// bindings returns every binding with its config name.
+func (k *KeyMap) bindings() []namedBinding {
+	return []namedBinding{
+		{"quit", &k.Quit},
+		{"toggleView", &k.ToggleView},
+		{"search", &k.Search},
+		{"filter", &k.Filter},
+		{"filterKind", &k.FilterKind},
+		{"filterReset", &k.FilterReset},
+		{"markdown", &k.Markdown},
+		{"timestamps", &k.Timestamps},
+		{"help", &k.Help},
+		{"palette", &k.Palette},
+		{"up", &k.Up},
+		{"down", &k.Down},
+		{"pageUp", &k.PageUp},
+		{"pageDown", &k.PageDown},
+		{"halfPageUp", &k.HalfPageUp},
+		{"halfPageDown", &k.HalfPageDown},
+		{"top", &k.Top},
+		{"bottom", &k.Bottom},
+		{"cyclePanels", &k.CyclePanels},
+		{"morePanels", &k.MorePanels},
+		{"fewerPanels", &k.FewerPanels},
+		{"layout", &k.Layout},
+		{"zoom", &k.Zoom},
+		{"nextPanel", &k.NextPanel},
+		{"prevPanel", &k.PrevPanel},
+		{"pin", &k.Pin},
+		{"swapLeft", &k.SwapLeft},
+		{"swapRight", &k.SwapRight},
+		{"open", &k.Open},
+		{"back", &k.Back},
+		{"nextBlock", &k.NextBlock},
+		{"prevBlock", &k.PrevBlock},
+		{"expandThinking", &k.ExpandThinking},
+		{"expandTools", &k.ExpandTools},
+		{"logSearch", &k.LogSearch},
+		{"nextMatch", &k.NextMatch},
+		{"prevMatch", &k.PrevMatch},
+		{"fullscreen", &k.Fullscreen},
+		{"sortByTime", &k.SortByTime},
+		{"sortBy", &k.SortBy},
+		{"groupBy", &k.GroupBy},
+		{"nextUnread", &k.NextUnread},
+		{"follow", &k.Follow},
+		{"collapse", &k.Collapse},
+		{"expand", &k.Expand},
+		{"toggleNode", &k.ToggleNode},
+		{"collapseAll", &k.CollapseAll},
+		{"expandAll", &k.ExpandAll},
+		{"menuToggle", &k.MenuToggle},
+		{"menuOnly", &k.MenuOnly},
+		{"menuShowAll", &k.MenuShowAll},
+		{"searchUp", &k.SearchUp},
+		{"searchDown", &k.SearchDown},
+		{"searchPageUp", &k.SearchPageUp},
+		{"searchPageDown", &k.SearchPageDown},
+		{"searchRegex", &k.SearchRegex},
+		{"searchAll", &k.SearchAll},
+		{"searchClear", &k.SearchClear},
+	}
+}
+
+// DefaultKeyMap returns the default key bindings.
+func DefaultKeyMap() *KeyMap {
+	return &KeyMap{
+		Quit:        binding("quit", "q", "ctrl+c"),
+		ToggleView:  binding("panel/tree mode", "t"),
+		Search:      binding("search all sessions", "s"),
+		Filter:      binding("filter menu", "F"),
+		FilterKind:  binding("toggle user/text/think/tool/result", "1", "2", "3", "4", "5"),
+		FilterReset: binding("show all blocks", "0"),
+		Markdown:    binding("markdown", "M"),
+		Timestamps:  binding("times", "T"),
+		Help:        binding("help", "?"),
+		Palette:     binding("command palette", "ctrl+p"),
+
+		Up:           binding("up", "k", "up"),
+		Down:         binding("down", "j", "down"),
+		PageUp:       binding("page up", "pgup", "ctrl+b", "b"),
+		PageDown:     binding("page down", "pgdown", "ctrl+f", " "),
+		HalfPageUp:   binding("half page up", "ctrl+u"),
+		HalfPageDown: binding("half page down", "ctrl+d", "d"),
+		Top:          binding("top", "g", "home"),
+		Bottom:       binding("bottom and follow", "G", "end"),
+
+		CyclePanels: binding("cycle panel count", "p"),
+		MorePanels:  binding("more panels", "+", "="),
+		FewerPanels: binding("fewer panels", "-"),
+		Layout:      binding("next layout", "L"),
+		Zoom:        binding("zoom", "z"),
+		NextPanel:   binding("next panel", "l", "right", "tab"),
+		PrevPanel:   binding("previous panel", "h", "left", "shift+tab"),
+		Pin:         binding("pin", "m"),
+		SwapLeft:    binding("swap left", "<"),
+		SwapRight:   binding("swap right", ">"),
+
+		Open:           binding("view logs / expand block", "enter"),
+		Back:           binding("back", "esc"),
+		NextBlock:      binding("next block", "]"),
+		PrevBlock:      binding("previous block", "["),
+		ExpandThinking: binding("expand thinking", "e"),
+		ExpandTools:    binding("expand tool IO", "E"),
+		LogSearch:      binding("search log", "/"),
+		NextMatch:      binding("next match", "n"),
+		PrevMatch:      binding("previous match", "N"),
+		Fullscreen:     binding("fullscreen", "f"),
+		SortByTime:     binding("sort by time", "r"),
+		SortBy:         binding("sort order", "o"),
+		GroupBy:        binding("grouping", "O"),
+		NextUnread:     binding("next unread", "u"),
+		Follow:         binding("follow", "a"),
+		Collapse:       binding("collapse", "h", "left"),
+		Expand:         binding("expand", "l", "right"),
+		ToggleNode:     binding("expand/collapse", " "),
+		CollapseAll:    binding("collapse all", "-"),
+		ExpandAll:      binding("expand all", "+", "*"),
+
+		MenuToggle:  binding("toggle", " ", "enter", "x"),
+		MenuOnly:    binding("only", "o"),
+		MenuShowAll: binding("show all", "a", "0"),
+
+		SearchUp:       binding("previous result", "up", "ctrl+k"),
+		SearchDown:     binding("next result", "down", "ctrl+j"),
+		SearchPageUp:   binding("previous page", "pgup"),
+		SearchPageDown: binding("next page", "pgdown"),
+		SearchRegex:    binding("regex", "ctrl+r"),
+		SearchAll:      binding("search scope", "ctrl+a"),
+		SearchClear:    binding("clear", "ctrl+u"),
+	}
+}
+
+// keyPresets holds alternative bindings applied on top of the defaults, by preset name.
+//
+//nolint:gochecknoglobals // package-level config
+var keyPresets = map[string]map[string][]string{
+	"default": {},
+	"emacs": {
+		"up":         {"ctrl+p", "up"},
+		"down":       {"ctrl+n", "down"},
+		"pageUp":     {"alt+v", "pgup"},
+		"pageDown":   {"ctrl+v", "pgdown"},
+		"top":        {"alt+<", "home"},
+		"bottom":     {"alt+>", "end"},
+		"nextPanel":  {"ctrl+f", "tab", "right"},
+		"prevPanel":  {"ctrl+b", "shift+tab", "left"},
+		"back":       {"ctrl+g", "esc"},
+		"logSearch":  {"ctrl+s", "/"},
+		"nextMatch":  {"alt+n", "n"},
+		"prevMatch":  {"alt+p", "N"},
+		"searchUp":   {"ctrl+p", "up"},
+		"searchDown": {"ctrl+n", "down"},
+		"palette":    {"alt+x"},
+	},
+	// Every movement goes by the arrow keys, Tab, PgUp/PgDn and Home/End, with Shift, Ctrl or Alt
+	// for the moves the plain keys already take; the letters are left to the other actions.
+	"arrows": {
+		"up":           {"up"},
+		"down":         {"down"},
+		"pageUp":       {"pgup"},
+		"pageDown":     {"pgdown"},
+		"halfPageUp":   {"ctrl+pgup"},
+		"halfPageDown": {"ctrl+pgdown"},
+		"top":          {"home"},
+		"bottom":       {"end"},
+		"nextPanel":    {"tab", "right"},
+		"prevPanel":    {"shift+tab", "left"},
+		"swapLeft":     {"shift+left"},
+		"swapRight":    {"shift+right"},
+		"nextBlock":    {"shift+down"},
+		"prevBlock":    {"shift+up"},
+		"nextMatch":    {"ctrl+down"},
+		"prevMatch":    {"ctrl+up"},
+		"nextUnread":   {"alt+down"},
+		"collapse":     {"left"},
+		"expand":       {"right"},
+		"searchUp":     {"up"},
+		"searchDown":   {"down"},
+		"menuToggle":   {" ", "enter"},
+		"menuShowAll":  {"0"},
+	},
+}
+
+// KeyPresets returns the names of the key binding presets, sorted.
+func KeyPresets() []string {
+	names := make([]string, 0, len(keyPresets))
+	for name := range keyPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewKeyMap returns the bindings of a preset with the given bindings replaced.
+// Bindings are keyed by their config name; an empty key list disables a binding.
+func NewKeyMap(preset string, overrides map[string][]string) (*KeyMap, error) {
+	if preset == "" {
+		preset = "default"
+	}
+	presetKeys, ok := keyPresets[preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown key preset %q (presets: %s)", preset, strings.Join(KeyPresets(), ", "))
+	}
+
+	k := DefaultKeyMap()
+	named := make(map[string]*key.Binding)
+	for _, nb := range k.bindings() {
+		named[nb.name] = nb.binding
+	}
+
+	for _, keys := range []map[string][]string{presetKeys, overrides} {
+		for name, bindingKeys := range keys {
+			b, ok := named[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown key binding %q", name)
+			}
+			rebind(b, bindingKeys)
+		}
+	}
+
+	return k, nil
+}
+
+// ViewportKeyMap returns the bindings used for scrolling the log viewport.
+func (k *KeyMap) ViewportKeyMap() viewport.KeyMap {
+	return viewport.KeyMap{
+		PageDown:     k.PageDown,
+		PageUp:       k.PageUp,
+		HalfPageUp:   k.HalfPageUp,
+		HalfPageDown: k.HalfPageDown,
+		Up:           k.Up,
+		Down:         k.Down,
+	}
+}
+
+// binding creates a binding whose help shows its first key.
+func binding(desc string, keys ...string) key.Binding {
+	b := key.NewBinding(key.WithHelp("", desc))
+	rebind(&b, keys)
+
+	return b
+}
+
+// rebind replaces the keys of a binding and updates its help key; no keys disables it.
+func rebind(b *key.Binding, keys []string) {
+	b.SetKeys(keys...)
+	b.SetEnabled(len(keys) > 0)
+	b.SetHelp(keyLabel(keys), b.Help().Desc)
+}
+
+// keyLabel returns how a binding's keys are shown in help: the first key,
+// or a range such as "1-5" for a binding whose keys select one of several items.
+func keyLabel(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	if len(keys) > 2 && isDigits(keys) {
+		return keys[0] + "-" + keys[len(keys)-1]
+	}
+
+	switch keys[0] {
+	case " ":
+		return "Space"
+	case "enter":
+		return "Enter"
+	case "esc":
+		return "Esc"
+	case "tab":
+		return "Tab"
+	}
+
+	return keys[0]
+}
+
+// isDigits returns whether every key is a single digit.
+func isDigits(keys []string) bool {
+	for _, k := range keys {
+		if len(k) != 1 || k[0] < '0' || k[0] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// keyIndex returns the position of the pressed key among a binding's keys, or -1.
+func keyIndex(b key.Binding, pressed string) int {
+	if !b.Enabled() {
+		return -1
+	}
+
+	return slices.Index(b.Keys(), pressed)
+}
+
+// helpItem is an entry of a help line: one description for one or more bindings.
+type helpItem struct {
+	desc     string
+	bindings []key.Binding
+}
+
+// help creates a help item.
+func help(desc string, bindings ...key.Binding) helpItem {
+	return helpItem{desc: desc, bindings: bindings}
+}
+
+// helpLine renders help items as "j/k: scroll | q: quit", skipping items without enabled bindings.
+func helpLine(items ...helpItem) string {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		var labels []string
+		for _, b := range item.bindings {
+			if b.Enabled() {
+				labels = append(labels, b.Help().Key)
+			}
+		}
+		if len(labels) == 0 {
+			continue
+		}
+		parts = append(parts, strings.Join(labels, "/")+": "+item.desc)
+	}
+
+	return strings.Join(parts, " | ")
+}
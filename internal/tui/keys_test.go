@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewKeyMap(t *testing.T) {
+	k, err := NewKeyMap("emacs", map[string][]string{"quit": {"Q"}, "zoom": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := k.Down.Keys(); !slices.Equal(got, []string{"ctrl+n", "down"}) {
+		t.Errorf("preset down = %q", got)
+	}
+	if got := k.Quit.Keys(); !slices.Equal(got, []string{"Q"}) {
+		t.Errorf("overridden quit = %q", got)
+	}
+	if k.Zoom.Enabled() {
+		t.Errorf("zoom bound to an empty key list is enabled")
+	}
+	if got := k.Pin.Keys(); !slices.Equal(got, DefaultKeyMap().Pin.Keys()) {
+		t.Errorf("pin not in the preset = %q, want the default", got)
+	}
+
+	if _, err := NewKeyMap("vi", nil); err == nil {
+		t.Errorf("unknown preset accepted")
+	}
+	if _, err := NewKeyMap("", map[string][]string{"teleport": {"x"}}); err == nil {
+		t.Errorf("unknown binding accepted")
+	}
+}
+
+func TestArrowsPresetMovesOnlyByArrowAndPagingKeys(t *testing.T) {
+	k, err := NewKeyMap("arrows", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	movement := []string{
+		"up", "down", "pageUp", "pageDown", "halfPageUp", "halfPageDown", "top", "bottom",
+		"nextPanel", "prevPanel", "swapLeft", "swapRight", "nextBlock", "prevBlock",
+		"nextMatch", "prevMatch", "nextUnread", "collapse", "expand", "searchUp", "searchDown",
+	}
+	allowed := []string{"up", "down", "left", "right", "pgup", "pgdown", "home", "end", "tab"}
+
+	for _, nb := range k.bindings() {
+		if !slices.Contains(movement, nb.name) {
+			continue
+		}
+		keys := nb.binding.Keys()
+		if len(keys) == 0 {
+			t.Errorf("%s is not bound", nb.name)
+		}
+		for _, name := range keys {
+			base := name
+			for _, modifier := range []string{"ctrl+", "alt+", "shift+"} {
+				base = strings.TrimPrefix(base, modifier)
+			}
+			if !slices.Contains(allowed, base) {
+				t.Errorf("%s is bound to %q", nb.name, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// minPanelWidth is the narrowest panel that still renders content.
+	minPanelWidth = 20
+	// minPanelHeight is the lowest panel that still renders content.
+	minPanelHeight = 6
+)
+
+// LayoutKind represents how panels are arranged in panel mode.
+type LayoutKind int
+
+const (
+	// LayoutColumns places all panels side by side.
+	LayoutColumns LayoutKind = iota
+	// LayoutRows stacks all panels vertically.
+	LayoutRows
+	// LayoutGrid arranges panels in a near-square grid derived from the panel count.
+	LayoutGrid
+	// LayoutFixed arranges panels in a fixed number of columns and rows (e.g. 2x2).
+	LayoutFixed
+)
+
+// Layout describes the panel arrangement.
+type Layout struct {
+	Kind LayoutKind
+	Cols int // columns for LayoutFixed
+	Rows int // rows for LayoutFixed
+}
+
+// layoutPresets is the order in which layouts are cycled.
+var layoutPresets = []Layout{ //nolint:gochecknoglobals // package-level config
+	{Kind: LayoutColumns},
+	{Kind: LayoutRows},
+	{Kind: LayoutGrid},
+	{Kind: LayoutFixed, Cols: 2, Rows: 2},
+	{Kind: LayoutFixed, Cols: 3, Rows: 2},
+}
+
+// ParseLayout parses a layout name: "columns", "rows", "grid" or "CxR" (e.g. "2x2", "3x2").
+func ParseLayout(s string) (Layout, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "columns", "cols":
+		return Layout{Kind: LayoutColumns}, nil
+	case "rows":
+		return Layout{Kind: LayoutRows}, nil
+	case "grid":
+		return Layout{Kind: LayoutGrid}, nil
+	}
+
+	colsStr, rowsStr, ok := strings.Cut(strings.ToLower(s), "x")
+	if ok {
+		cols, errCols := strconv.Atoi(colsStr)
+		rows, errRows := strconv.Atoi(rowsStr)
+		if errCols == nil && errRows == nil && cols > 0 && rows > 0 {
+			return Layout{Kind: LayoutFixed, Cols: cols, Rows: rows}, nil
+		}
+	}
+
+	return Layout{}, fmt.Errorf("invalid layout %q: use columns, rows, grid or CxR (e.g. 2x2)", s)
+}
+
+// String returns the layout name accepted by ParseLayout.
+func (l Layout) String() string {
+	switch l.Kind {
+	case LayoutRows:
+		return "rows"
+	case LayoutGrid:
+		return "grid"
+	case LayoutFixed:
+		return fmt.Sprintf("%dx%d", l.Cols, l.Rows)
+	default:
+		return "columns"
+	}
+}
+
+// next returns the layout following l in the preset cycle.
+func (l Layout) next() Layout {
+	for i, preset := range layoutPresets {
+		if preset == l {
+			return layoutPresets[(i+1)%len(layoutPresets)]
+		}
+	}
+
+	return layoutPresets[0]
+}
+
+// grid returns the number of columns and rows used for the given panel count.
+func (l Layout) grid(panels int) (int, int) {
+	panels = max(1, panels)
+
+	var cols int
+	switch l.Kind {
+	case LayoutRows:
+		cols = 1
+	case LayoutGrid:
+		cols = int(math.Ceil(math.Sqrt(float64(panels))))
+	case LayoutFixed:
+		cols = min(l.Cols, panels)
+	default:
+		cols = panels
+	}
+
+	return cols, (panels + cols - 1) / cols
+}
+
+// maxPanels returns the largest panel count that fits in the given area.
+// Fixed layouts hold at most their number of cells, and fewer when the cells would be too small.
+func (l Layout) maxPanels(width, height int) int {
+	maxCount := 1
+	for n := 2; ; n++ {
+		if l.Kind == LayoutFixed && n > l.Cols*l.Rows {
+			return maxCount
+		}
+		cols, rows := l.grid(n)
+		if width/cols < minPanelWidth || height/rows < minPanelHeight {
+			return maxCount
+		}
+		maxCount = n
+	}
+}
+
+// panelRect is the position and size of a panel on screen.
+type panelRect struct {
+	x, y          int
+	width, height int
+}
+
+// contains reports whether the point lies inside the rectangle.
+func (r panelRect) contains(x, y int) bool {
+	return x >= r.x && x < r.x+r.width && y >= r.y && y < r.y+r.height
+}
+
+// rects returns the rectangle of each panel in the given area.
+// Space is split proportionally; a partially filled last row stretches its panels.
+func (l Layout) rects(panels, width, height int) []panelRect {
+	cols, rows := l.grid(panels)
+	heights := splitEvenly(height, rows)
+
+	result := make([]panelRect, 0, panels)
+	y := 0
+	for row := range rows {
+		inRow := min(cols, panels-row*cols)
+		x := 0
+		for _, w := range splitEvenly(width, inRow) {
+			result = append(result, panelRect{x: x, y: y, width: w, height: heights[row]})
+			x += w
+		}
+		y += heights[row]
+	}
+
+	return result
+}
+
+// splitEvenly splits total into n parts that differ by at most one.
+func splitEvenly(total, n int) []int {
+	parts := make([]int, n)
+	for i := range parts {
+		parts[i] = total / n
+		if i < total%n {
+			parts[i]++
+		}
+	}
+
+	return parts
+}
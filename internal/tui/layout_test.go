@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseLayout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Layout
+		wantErr bool
+	}{
+		{in: "", want: Layout{Kind: LayoutColumns}},
+		{in: "columns", want: Layout{Kind: LayoutColumns}},
+		{in: " Cols ", want: Layout{Kind: LayoutColumns}},
+		{in: "rows", want: Layout{Kind: LayoutRows}},
+		{in: "GRID", want: Layout{Kind: LayoutGrid}},
+		{in: "2x2", want: Layout{Kind: LayoutFixed, Cols: 2, Rows: 2}},
+		{in: "3X1", want: Layout{Kind: LayoutFixed, Cols: 3, Rows: 1}},
+		{in: "0x2", wantErr: true},
+		{in: "2x-1", wantErr: true},
+		{in: "2x", wantErr: true},
+		{in: "x", wantErr: true},
+		{in: "diagonal", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLayout(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLayout(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLayout(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if err == nil {
+			if again, _ := ParseLayout(got.String()); again != got {
+				t.Errorf("ParseLayout(%q) = %+v, does not round-trip %+v", got.String(), again, got)
+			}
+		}
+	}
+}
+
+func TestLayoutNextCyclesThroughPresets(t *testing.T) {
+	l := layoutPresets[0]
+	for range layoutPresets {
+		l = l.next()
+	}
+	if l != layoutPresets[0] {
+		t.Fatalf("cycle ended at %v, want %v", l, layoutPresets[0])
+	}
+	if got := (Layout{Kind: LayoutFixed, Cols: 4, Rows: 4}).next(); got != layoutPresets[0] {
+		t.Fatalf("next of a layout outside the cycle = %v, want %v", got, layoutPresets[0])
+	}
+}
+
+func TestLayoutMaxPanels(t *testing.T) {
+	tests := []struct {
+		layout        string
+		width, height int
+		want          int
+	}{
+		{layout: "columns", width: 100, height: 30, want: 5},
+		{layout: "columns", width: 19, height: 30, want: 1},
+		{layout: "rows", width: 100, height: 30, want: 5},
+		{layout: "grid", width: 100, height: 30, want: 25},
+		{layout: "grid", width: 40, height: 12, want: 4},
+		{layout: "2x2", width: 200, height: 100, want: 4},
+		{layout: "3x2", width: 200, height: 100, want: 6},
+		// Cells of a fixed layout that would be too narrow hold fewer panels.
+		{layout: "3x2", width: 50, height: 100, want: 2},
+		{layout: "2x2", width: 30, height: 100, want: 1},
+	}
+
+	for _, tt := range tests {
+		l, err := ParseLayout(tt.layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.maxPanels(tt.width, tt.height); got != tt.want {
+			t.Errorf("%s.maxPanels(%d, %d) = %d, want %d", tt.layout, tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestLayoutRects(t *testing.T) {
+	tests := []struct {
+		layout string
+		panels int
+		want   string
+	}{
+		{layout: "columns", panels: 3, want: "[{0 0 34 30} {34 0 33 30} {67 0 33 30}]"},
+		{layout: "rows", panels: 2, want: "[{0 0 100 15} {0 15 100 15}]"},
+		{layout: "grid", panels: 4, want: "[{0 0 50 15} {50 0 50 15} {0 15 50 15} {50 15 50 15}]"},
+		// A partially filled last row stretches its panels.
+		{layout: "grid", panels: 3, want: "[{0 0 50 15} {50 0 50 15} {0 15 100 15}]"},
+		{layout: "3x2", panels: 2, want: "[{0 0 50 30} {50 0 50 30}]"},
+		{layout: "3x2", panels: 5, want: "[{0 0 34 15} {34 0 33 15} {67 0 33 15} {0 15 50 15} {50 15 50 15}]"},
+	}
+
+	for _, tt := range tests {
+		l, err := ParseLayout(tt.layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := l.rects(tt.panels, 100, 30)
+		if s := fmt.Sprint(got); s != tt.want {
+			t.Errorf("%s.rects(%d) = %s, want %s", tt.layout, tt.panels, s, tt.want)
+		}
+		for i, r := range got {
+			if !r.contains(r.x, r.y) || r.contains(r.x+r.width, r.y) || r.contains(r.x, r.y+r.height) {
+				t.Errorf("%s.rects(%d)[%d] = %+v does not contain exactly its own area", tt.layout, tt.panels, i, r)
+			}
+		}
+	}
+}
@@ -1,9 +1,16 @@
 package tui
 
 import (
+	"time"
+
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sters/cc-session-tailing/internal/filter"
 	"github.com/sters/cc-session-tailing/internal/parser"
+	"github.com/sters/cc-session-tailing/internal/render"
+	"github.com/sters/cc-session-tailing/internal/search"
 	"github.com/sters/cc-session-tailing/internal/session"
+	"github.com/sters/cc-session-tailing/internal/state"
+	"github.com/sters/cc-session-tailing/internal/theme"
 	"github.com/sters/cc-session-tailing/internal/watcher"
 )
 
@@ -14,60 +21,115 @@ type FileUpdateMsg struct {
 
 // Model is the bubbletea model for the TUI.
 type Model struct {
-	manager   *session.Manager
-	watcher   *watcher.Watcher
-	renderer  *Renderer
-	width     int
-	height    int
-	scrollPos []int // scroll position for each panel (-1 = follow bottom, >= 0 = fixed start line)
-	ready     bool
-	viewMode  ViewMode
-	treeView  *TreeView
+	manager  *session.Manager
+	watcher  *watcher.Watcher
+	renderer *Renderer
+	width    int
+	height   int
+	// scrollPos holds the panel scroll position of each session (missing = follow bottom, >= 0 = fixed start line).
+	// It is keyed by session ID so that a panel keeps its position when LRU reorders panels.
+	scrollPos map[string]int
+	// focusedPanel is the panel that scroll, pin and swap keys act on.
+	focusedPanel int
+	// focusedSession is the session shown in the focused panel; focus follows it when panels reorder.
+	focusedSession string
+	layout         Layout
+	zoomed         bool // focused panel is maximized
+	ready          bool
+	viewMode       ViewMode
+	treeView       *TreeView
+	search         *GlobalSearch
+	panelFilter    *filter.Filter // blocks shown in panel mode
+	filterMenu     *FilterMenu
+	help           *HelpOverlay
+	palette        *Palette
+	themes         map[string]theme.Spec // user themes offered by the palette
+	notice         string                // result of a palette command, shown until the next key
+	markdown       bool                  // render assistant text as Markdown
+	timestamps     render.TimestampMode
+	keys           *KeyMap
 }
 
 // NewModel creates a new TUI model with panel mode.
 func NewModel(manager *session.Manager, w *watcher.Watcher) *Model {
-	panels := manager.PanelCount()
-	scrollPos := make([]int, panels)
-	for i := range scrollPos {
-		scrollPos[i] = -1 // Follow mode by default.
-	}
+	panelFilter := filter.New()
+	renderer := NewRenderer(NewStyles())
+	renderer.SetFilter(panelFilter)
 
 	return &Model{
-		manager:   manager,
-		watcher:   w,
-		renderer:  NewRenderer(NewStyles()),
-		scrollPos: scrollPos,
-		viewMode:  ViewModePanel,
-		treeView:  NewTreeView(manager),
+		manager:     manager,
+		watcher:     w,
+		renderer:    renderer,
+		scrollPos:   make(map[string]int),
+		viewMode:    ViewModePanel,
+		treeView:    NewTreeView(manager),
+		search:      NewGlobalSearch(manager, w.ProjectPath()),
+		panelFilter: panelFilter,
+		filterMenu:  NewFilterMenu(),
+		help:        NewHelpOverlay(),
+		palette:     NewPalette(),
+		keys:        DefaultKeyMap(),
 	}
 }
 
 // NewModelWithMode creates a new TUI model with the specified view mode.
 func NewModelWithMode(manager *session.Manager, w *watcher.Watcher, mode ViewMode) *Model {
-	panels := manager.PanelCount()
-	scrollPos := make([]int, panels)
-	for i := range scrollPos {
-		scrollPos[i] = -1 // Follow mode by default.
-	}
+	panelFilter := filter.New()
+	renderer := NewRenderer(NewStyles())
+	renderer.SetFilter(panelFilter)
 
 	return &Model{
-		manager:   manager,
-		watcher:   w,
-		renderer:  NewRenderer(NewStyles()),
-		scrollPos: scrollPos,
-		viewMode:  mode,
-		treeView:  NewTreeView(manager),
+		manager:     manager,
+		watcher:     w,
+		renderer:    renderer,
+		scrollPos:   make(map[string]int),
+		viewMode:    mode,
+		treeView:    NewTreeView(manager),
+		search:      NewGlobalSearch(manager, w.ProjectPath()),
+		panelFilter: panelFilter,
+		filterMenu:  NewFilterMenu(),
+		help:        NewHelpOverlay(),
+		palette:     NewPalette(),
+		keys:        DefaultKeyMap(),
 	}
 }
 
+// SetKeyMap sets the key bindings of all views.
+func (m *Model) SetKeyMap(k *KeyMap) {
+	m.keys = k
+	m.treeView.setKeyMap(k)
+	m.filterMenu.keys = k
+	m.search.keys = k
+	m.help.keys = k
+	m.palette.keys = k
+}
+
+// SetThemes sets the user themes that can be switched to besides the built-in themes.
+func (m *Model) SetThemes(themes map[string]theme.Spec) {
+	m.themes = themes
+}
+
 // Init initializes the model.
 func (m *Model) Init() tea.Cmd {
 	return tea.Batch(
 		waitForFileEvents(m.watcher),
+		clockTick(),
 	)
 }
 
+// ClockTickMsg is sent periodically to refresh relative timestamps.
+type ClockTickMsg struct{}
+
+// clockInterval is how often relative timestamps are refreshed.
+const clockInterval = 30 * time.Second
+
+// clockTick returns a command that sends a ClockTickMsg after clockInterval.
+func clockTick() tea.Cmd {
+	return tea.Tick(clockInterval, func(_ time.Time) tea.Msg {
+		return ClockTickMsg{}
+	})
+}
+
 // waitForFileEvents waits for file events from the watcher.
 func waitForFileEvents(w *watcher.Watcher) tea.Cmd {
 	return func() tea.Msg {
@@ -101,6 +163,226 @@ func (m *Model) processFileUpdate(event watcher.Event) {
 	}
 }
 
+// openSearchResult shows a cross-session search hit in tree mode.
+// Sessions read from files the manager does not hold are shown read-only, without registering them,
+// so that they take no panel and are not saved with the UI state.
+func (m *Model) openSearchResult(result search.Result) {
+	if m.viewMode != ViewModeTree {
+		m.viewMode = ViewModeTree
+		m.treeView.RefreshSessionsSortedAndReset()
+	}
+
+	highlight := ""
+	if !m.search.regex {
+		highlight = m.search.input
+	}
+
+	if result.Archived && m.manager.GetSession(result.SessionID) == nil {
+		messages, err := parser.ParseFile(result.Path)
+		if err != nil {
+			m.setNotice("Cannot open " + result.SessionID + ": " + err.Error())
+
+			return
+		}
+		sess := &session.Session{ID: result.SessionID, Path: result.Path, Messages: messages}
+		m.treeView.OpenArchived(sess, result.MessageIndex, highlight)
+
+		return
+	}
+	m.treeView.OpenSession(result.SessionID, result.MessageIndex, highlight)
+}
+
+// activeFilter returns the block filter of the current view mode.
+func (m *Model) activeFilter() *filter.Filter {
+	if m.viewMode == ViewModeTree {
+		return m.treeView.Filter()
+	}
+
+	return m.panelFilter
+}
+
+// openFilterMenu opens the filter menu for the current view mode.
+// Tools are listed from the sessions on screen.
+func (m *Model) openFilterMenu() {
+	sessions := m.manager.GetPanelSessions()
+	if m.viewMode == ViewModeTree {
+		sessions = []*session.Session{m.treeView.SelectedSession()}
+	}
+	m.filterMenu.Open(m.activeFilter(), sessions)
+}
+
+// applyFilter re-renders the current view after the filter changed.
+func (m *Model) applyFilter() {
+	if m.viewMode == ViewModeTree {
+		m.treeView.RefreshLog()
+
+		return
+	}
+	m.markPanelsRead()
+}
+
+// setMarkdown sets whether both view modes render assistant text as Markdown.
+func (m *Model) setMarkdown(on bool) {
+	m.markdown = on
+	m.renderer.SetMarkdown(on)
+	m.treeView.SetMarkdown(on)
+	if m.ready && m.viewMode == ViewModePanel {
+		m.markPanelsRead()
+	}
+}
+
+// setTimestamps sets how both view modes show message times.
+func (m *Model) setTimestamps(mode render.TimestampMode) {
+	m.timestamps = mode
+	m.renderer.SetTimestamps(mode)
+	m.treeView.SetTimestamps(mode)
+	if m.ready && m.viewMode == ViewModePanel {
+		m.markPanelsRead()
+	}
+}
+
+// RestoreState applies UI state saved by a previous run.
+// Read cursors are restored so that activity since the last run shows as unread.
+// On the first run, everything already on disk is treated as read.
+func (m *Model) RestoreState(st *state.State) {
+	for id, pos := range st.PanelScroll {
+		m.scrollPos[id] = pos
+	}
+
+	if len(st.Seen) > 0 {
+		m.manager.SetReadCursors(st.Seen)
+	} else {
+		m.manager.MarkAllRead()
+	}
+
+	m.treeView.RestoreState(st)
+	m.setMarkdown(st.Markdown)
+	m.setTimestamps(render.ParseTimestampMode(st.Timestamps))
+}
+
+// SaveState returns the current UI state for persisting.
+func (m *Model) SaveState() *state.State {
+	st := state.New()
+	st.PanelCount = m.manager.PanelCount()
+	st.Layout = m.layout.String()
+	st.PanelScroll = make(map[string]int, len(m.scrollPos))
+	for id, pos := range m.scrollPos {
+		st.PanelScroll[id] = pos
+	}
+	st.Seen = m.manager.ReadCursors()
+	st.Markdown = m.markdown
+	if m.timestamps != render.TimestampsOff {
+		st.Timestamps = m.timestamps.String()
+	}
+	m.treeView.SaveState(st)
+
+	return st
+}
+
+// panelRects returns the rectangle of each panel.
+// When zoomed, the focused panel takes the whole area and the others get an empty rectangle.
+func (m *Model) panelRects() []panelRect {
+	panels := m.manager.PanelCount()
+	width, height := m.width, m.height-2 // Leave room for help line.
+
+	if m.zoomed {
+		rects := make([]panelRect, panels)
+		if m.focusedPanel < panels {
+			rects[m.focusedPanel] = panelRect{width: width, height: height}
+		}
+
+		return rects
+	}
+
+	return m.layout.rects(panels, width, height)
+}
+
+// panelSize returns the width and height of a panel.
+func (m *Model) panelSize(i int) (int, int) {
+	rects := m.panelRects()
+	if i < 0 || i >= len(rects) {
+		return 0, 0
+	}
+
+	return rects[i].width, rects[i].height
+}
+
+// SetLayout sets the panel layout used in panel mode.
+func (m *Model) SetLayout(layout Layout) {
+	m.layout = layout
+}
+
+// Layout returns the panel layout used in panel mode.
+func (m *Model) Layout() Layout {
+	return m.layout
+}
+
+// panelScroll returns the panel scroll position of a session (-1 = follow bottom).
+func (m *Model) panelScroll(sess *session.Session) int {
+	if sess == nil {
+		return -1
+	}
+	if pos, ok := m.scrollPos[sess.ID]; ok {
+		return pos
+	}
+
+	return -1
+}
+
+// setPanelScroll sets the panel scroll position of a session (-1 = follow bottom).
+func (m *Model) setPanelScroll(sess *session.Session, pos int) {
+	if sess == nil {
+		return
+	}
+	if pos < 0 {
+		delete(m.scrollPos, sess.ID)
+
+		return
+	}
+	m.scrollPos[sess.ID] = pos
+}
+
+// focusedPanelSession returns the session shown in the focused panel.
+func (m *Model) focusedPanelSession() *session.Session {
+	sessions := m.manager.GetPanelSessions()
+	if m.focusedPanel < 0 || m.focusedPanel >= len(sessions) {
+		return nil
+	}
+
+	return sessions[m.focusedPanel]
+}
+
+// syncPanelFocus moves panel focus along with the focused session when panels reorder.
+// If the session is no longer shown, focus stays on the slot and takes over its session.
+func (m *Model) syncPanelFocus() {
+	sessions := m.manager.GetPanelSessions()
+	for i, sess := range sessions {
+		if sess != nil && sess.ID == m.focusedSession {
+			m.focusedPanel = i
+
+			return
+		}
+	}
+
+	m.focusedPanel = max(0, min(m.focusedPanel, len(sessions)-1))
+	m.focusedSession = ""
+	if sess := m.focusedPanelSession(); sess != nil {
+		m.focusedSession = sess.ID
+	}
+}
+
+// markPanelsRead advances the read cursors of sessions shown in panels.
+func (m *Model) markPanelsRead() {
+	rects := m.panelRects()
+	for i, sess := range m.manager.GetPanelSessions() {
+		if sess == nil || i >= len(rects) || rects[i].width == 0 {
+			continue
+		}
+		count := m.renderer.ReadCount(sess, rects[i].width, rects[i].height, m.panelScroll(sess))
+		m.manager.MarkRead(sess.ID, count)
+	}
+}
+
 // ViewMode returns the current view mode.
 func (m *Model) ViewMode() ViewMode {
 	return m.viewMode
@@ -116,6 +398,7 @@ func (m *Model) SetViewMode(mode ViewMode) {
 func (m *Model) ToggleViewMode() tea.Cmd {
 	if m.viewMode == ViewModeTree {
 		m.viewMode = ViewModePanel
+		m.markPanelsRead()
 
 		return nil
 	}
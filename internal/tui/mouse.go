@@ -0,0 +1,164 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// wheelLines is the number of lines scrolled per mouse wheel step.
+	wheelLines = 3
+	// doubleClickInterval is the longest time between the clicks of a double-click.
+	doubleClickInterval = 400 * time.Millisecond
+	// minTreeWidth and minLogWidth limit how far the tree/log divider can be dragged.
+	minTreeWidth = 12
+	minLogWidth  = 20
+)
+
+// treeClick records a click on a tree item.
+type treeClick struct {
+	index int
+	at    time.Time
+}
+
+// updateMouse handles mouse events. Overlays ignore the mouse, except that the wheel scrolls the help.
+func (m *Model) updateMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.help.Active() {
+		switch {
+		case msg.Button == tea.MouseButtonWheelUp:
+			m.help.Scroll(-wheelLines)
+		case msg.Button == tea.MouseButtonWheelDown:
+			m.help.Scroll(wheelLines)
+		}
+
+		return m, nil
+	}
+	if m.palette.Active() || m.search.Active() || m.filterMenu.Active() {
+		return m, nil
+	}
+
+	if m.viewMode == ViewModeTree {
+		return m, m.treeView.HandleMouse(msg)
+	}
+
+	m.updatePanelMouse(msg)
+
+	return m, nil
+}
+
+// updatePanelMouse scrolls the panel under the wheel and focuses a clicked panel.
+func (m *Model) updatePanelMouse(msg tea.MouseMsg) {
+	m.syncPanelFocus()
+
+	panel := -1
+	for i, rect := range m.panelRects() {
+		if rect.width > 0 && rect.contains(msg.X, msg.Y) {
+			panel = i
+
+			break
+		}
+	}
+	if panel < 0 {
+		return
+	}
+
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		m.scrollPanel(panel, -wheelLines)
+	case msg.Button == tea.MouseButtonWheelDown:
+		m.scrollPanel(panel, wheelLines)
+	case msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress:
+		m.setFocusedPanel(panel)
+	default:
+		return
+	}
+	m.markPanelsRead()
+}
+
+// HandleMouse handles a mouse event in tree mode:
+// wheel scrolling, selecting and opening tree items, selecting log blocks and dragging the divider.
+func (tv *TreeView) HandleMouse(msg tea.MouseMsg) tea.Cmd {
+	treeWidth := tv.currentTreeWidth()
+
+	if tv.dragging {
+		if msg.Action == tea.MouseActionRelease {
+			tv.dragging = false
+		} else {
+			tv.treeWidth = clampTreeWidth(msg.X+1, tv.width)
+			tv.updateLayout()
+		}
+
+		return nil
+	}
+
+	inTree := msg.X < treeWidth
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp || msg.Button == tea.MouseButtonWheelDown:
+		step := 1
+		if msg.Button == tea.MouseButtonWheelUp {
+			step = -1
+		}
+		if inTree {
+			tv.moveTreeSelection(step)
+		} else {
+			tv.log.ScrollBy(step * wheelLines)
+			tv.markRead()
+		}
+
+	case msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress:
+		// The divider is the border column on either side of the tree/log boundary.
+		if treeWidth > 0 && (msg.X == treeWidth-1 || msg.X == treeWidth) {
+			tv.dragging = true
+
+			return nil
+		}
+		if inTree {
+			tv.clickTree(msg.Y)
+
+			return nil
+		}
+		tv.setFocus(FocusLog)
+		if tv.log.ClickLine(msg.Y) {
+			tv.markRead()
+		}
+	}
+
+	return nil
+}
+
+// clickTree selects the tree item on the clicked row; a double-click opens its log.
+func (tv *TreeView) clickTree(row int) {
+	if !tv.tree.SelectAt(row) {
+		return
+	}
+
+	tv.setFocus(FocusTree)
+	tv.showSelection()
+
+	now := time.Now()
+	index := tv.tree.SelectedIndex()
+	if tv.lastClick.index == index && now.Sub(tv.lastClick.at) <= doubleClickInterval {
+		tv.setFocus(FocusLog)
+		tv.lastClick = treeClick{index: -1}
+
+		return
+	}
+	tv.lastClick = treeClick{index: index, at: now}
+}
+
+// moveTreeSelection moves the tree selection by delta items.
+func (tv *TreeView) moveTreeSelection(delta int) {
+	for ; delta < 0; delta++ {
+		tv.tree.MoveUp()
+	}
+	for ; delta > 0; delta-- {
+		tv.tree.MoveDown()
+	}
+	tv.showSelection()
+}
+
+// clampTreeWidth limits a tree width so that both the tree and the log stay usable.
+func clampTreeWidth(width, total int) int {
+	return max(minTreeWidth, min(width, total-minLogWidth))
+}
@@ -0,0 +1,260 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/sters/cc-session-tailing/internal/fuzzy"
+	"github.com/sters/cc-session-tailing/internal/theme"
+)
+
+// paletteWidth is the widest the palette gets.
+const paletteWidth = 100
+
+// paletteRows is the most rows the palette lists at once.
+const paletteRows = 20
+
+// paletteKindWidth is the width of the kind column.
+const paletteKindWidth = 9
+
+// paletteItem is a session or command listed by the palette.
+type paletteItem struct {
+	kind    string         // "session", "subagent" or "command"
+	text    string         // matched against the query and shown
+	key     string         // key of the equivalent binding, shown after commands
+	session string         // session to jump to; empty for commands
+	run     func() tea.Cmd // action of a command; nil for sessions
+}
+
+// paletteMatch is an item that matches the query.
+type paletteMatch struct {
+	item      *paletteItem
+	score     int
+	positions []int // matched runes of item.text
+}
+
+// Palette is an overlay that fuzzy-finds sessions and commands.
+type Palette struct {
+	active   bool
+	input    string
+	items    []paletteItem
+	matches  []paletteMatch
+	selected int
+	offset   int
+	width    int
+	height   int
+	keys     *KeyMap
+}
+
+// NewPalette creates a palette.
+func NewPalette() *Palette {
+	return &Palette{keys: DefaultKeyMap()}
+}
+
+// Open shows the palette with an empty query, listing the given items.
+func (p *Palette) Open(items []paletteItem) {
+	p.active = true
+	p.input = ""
+	p.items = items
+	p.filter()
+}
+
+// Close hides the palette.
+func (p *Palette) Close() {
+	p.active = false
+	p.items = nil
+	p.matches = nil
+}
+
+// Active returns whether the palette is shown.
+func (p *Palette) Active() bool {
+	return p.active
+}
+
+// SetSize sets the dimensions of the area the palette is centered in.
+func (p *Palette) SetSize(width, height int) {
+	p.width = width
+	p.height = height
+}
+
+// Update handles a key while the palette is shown.
+// It returns the chosen item, after which the palette is closed.
+func (p *Palette) Update(msg tea.KeyMsg) *paletteItem {
+	switch k := p.keys; {
+	case key.Matches(msg, k.Back, k.Palette):
+		p.Close()
+	case key.Matches(msg, k.Open):
+		if p.selected < len(p.matches) {
+			item := p.matches[p.selected].item
+			p.Close()
+
+			return item
+		}
+	case key.Matches(msg, k.SearchUp):
+		p.moveSelection(-1)
+	case key.Matches(msg, k.SearchDown):
+		p.moveSelection(1)
+	case key.Matches(msg, k.SearchPageUp):
+		p.moveSelection(-p.listHeight())
+	case key.Matches(msg, k.SearchPageDown):
+		p.moveSelection(p.listHeight())
+	case key.Matches(msg, k.SearchClear):
+		p.input = ""
+		p.filter()
+	case msg.Type == tea.KeyBackspace:
+		if p.input != "" {
+			runes := []rune(p.input)
+			p.input = string(runes[:len(runes)-1])
+			p.filter()
+		}
+	case msg.Type == tea.KeySpace:
+		p.input += " "
+		p.filter()
+	case msg.Type == tea.KeyRunes:
+		p.input += string(msg.Runes)
+		p.filter()
+	}
+
+	return nil
+}
+
+// filter matches the items against the query, best matches first.
+// Items keep their order among equal scores, so an empty query lists them as given.
+func (p *Palette) filter() {
+	p.matches = p.matches[:0]
+	for i := range p.items {
+		score, positions, ok := fuzzy.Match(p.input, p.items[i].text)
+		if ok {
+			p.matches = append(p.matches, paletteMatch{item: &p.items[i], score: score, positions: positions})
+		}
+	}
+	sort.SliceStable(p.matches, func(i, j int) bool {
+		return p.matches[i].score > p.matches[j].score
+	})
+	p.selected = 0
+	p.offset = 0
+}
+
+func (p *Palette) moveSelection(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+
+	p.selected = max(0, min(p.selected+delta, len(p.matches)-1))
+
+	height := p.listHeight()
+	if p.selected < p.offset {
+		p.offset = p.selected
+	}
+	if p.selected >= p.offset+height {
+		p.offset = p.selected - height + 1
+	}
+}
+
+// listHeight returns the number of rows that fit on screen.
+func (p *Palette) listHeight() int {
+	// Border (2), input, separator, footer and a margin line above and below.
+	return max(1, min(paletteRows, p.height-7))
+}
+
+// View renders the palette centered on screen.
+func (p *Palette) View() string {
+	c := theme.Colors()
+	glyphs := theme.Symbols()
+	k := p.keys
+	inputStyle := lipgloss.NewStyle().Foreground(c.Text).Bold(true)
+	labelStyle := lipgloss.NewStyle().Foreground(c.Muted)
+	selectedStyle := lipgloss.NewStyle().Background(c.Selection)
+
+	innerWidth := max(20, min(p.width-6, paletteWidth))
+
+	lines := []string{
+		inputStyle.Render("> "+p.input+"_") + labelStyle.Render(fmt.Sprintf("  %d/%d", len(p.matches), len(p.items))),
+		labelStyle.Render(strings.Repeat(glyphs.HLine, innerWidth)),
+	}
+
+	end := min(len(p.matches), p.offset+p.listHeight())
+	for i := p.offset; i < end; i++ {
+		lines = append(lines, p.renderRow(p.matches[i], i == p.selected, innerWidth, selectedStyle))
+	}
+	if len(p.matches) == 0 {
+		lines = append(lines, labelStyle.Render("No matches"))
+	}
+	for len(lines) < p.listHeight()+2 {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, labelStyle.Render(truncateLine(helpLine(
+		help("run / jump", k.Open),
+		help("select", k.SearchUp, k.SearchDown),
+		help("clear", k.SearchClear),
+		help("close", k.Back),
+	), innerWidth)))
+
+	box := lipgloss.NewStyle().
+		Border(glyphs.Border).
+		BorderForeground(c.Accent).
+		Padding(0, 1).
+		Width(innerWidth + 2).
+		Render(strings.Join(lines, "\n"))
+
+	return lipgloss.Place(p.width, p.height, lipgloss.Center, lipgloss.Center, box)
+}
+
+// renderRow renders a match: its kind, its text with the matched characters highlighted, and its key.
+func (p *Palette) renderRow(m paletteMatch, selected bool, width int, selectedStyle lipgloss.Style) string {
+	c := theme.Colors()
+	kindStyle := lipgloss.NewStyle().Foreground(c.Muted)
+	sessionStyle := lipgloss.NewStyle().Foreground(c.User)
+	matchStyle := lipgloss.NewStyle().Foreground(c.Highlight).Bold(true)
+
+	kind := m.item.kind
+	if theme.Plain() && selected {
+		// The selection is shown by a marker instead of the background color.
+		kind = "> " + kind
+	}
+	kind = runewidth.FillRight(runewidth.Truncate(kind, paletteKindWidth, ""), paletteKindWidth) + " "
+
+	keyText := ""
+	if m.item.key != "" {
+		keyText = "  " + m.item.key
+	}
+	textWidth := max(1, width-paletteKindWidth-1-runewidth.StringWidth(keyText))
+	text := truncateLine(m.item.text, textWidth)
+	pad := strings.Repeat(" ", max(0, textWidth-runewidth.StringWidth(text)))
+
+	if selected {
+		return selectedStyle.Width(width).Render(kind + text + pad + keyText)
+	}
+
+	textStyle := lipgloss.NewStyle().Foreground(c.Text)
+	if m.item.session != "" {
+		textStyle = sessionStyle
+	}
+	matched := make(map[int]bool, len(m.positions))
+	for _, pos := range m.positions {
+		matched[pos] = true
+	}
+	// Runs of matched and unmatched runes are rendered as one segment each.
+	var b strings.Builder
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && matched[end] == matched[start] {
+			end++
+		}
+		style := textStyle
+		if matched[start] {
+			style = matchStyle
+		}
+		b.WriteString(style.Render(string(runes[start:end])))
+		start = end
+	}
+
+	return kindStyle.Render(kind) + b.String() + pad + kindStyle.Render(keyText)
+}
@@ -1,80 +1,107 @@
 package tui
 
 import (
-	"encoding/json"
-	"fmt"
-	"sort"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-runewidth"
-	"github.com/sters/cc-session-tailing/internal/parser"
+	"github.com/sters/cc-session-tailing/internal/filter"
+	"github.com/sters/cc-session-tailing/internal/render"
 	"github.com/sters/cc-session-tailing/internal/session"
+	"github.com/sters/cc-session-tailing/internal/theme"
 )
 
 // Styles holds all panel styles.
 type Styles struct {
-	PanelBorder    lipgloss.Style
-	HeaderStyle    lipgloss.Style
-	ThinkStyle     lipgloss.Style
-	TextStyle      lipgloss.Style
-	ToolStyle      lipgloss.Style
-	ToolInputStyle lipgloss.Style
-	UserStyle      lipgloss.Style
-	LabelStyle     lipgloss.Style
-	EmptyStyle     lipgloss.Style
-	HelpStyle      lipgloss.Style
+	PanelBorder lipgloss.Style
+	HeaderStyle lipgloss.Style
+	EmptyStyle  lipgloss.Style
+	HelpStyle   lipgloss.Style
+	Content     *render.Styles // message content
 }
 
 // NewStyles creates a new Styles instance.
 func NewStyles() *Styles {
+	c := theme.Colors()
+
 	return &Styles{
 		PanelBorder: lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("240")),
+			Border(theme.Symbols().Border).
+			BorderForeground(c.Border),
 		HeaderStyle: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("212")).
-			Background(lipgloss.Color("235")),
-		ThinkStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("243")).
-			Italic(true),
-		TextStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("252")),
-		ToolStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("214")).
-			Bold(true),
-		ToolInputStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("250")),
-		UserStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("117")).
-			Bold(true),
-		LabelStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")),
+			Foreground(c.Accent).
+			Background(c.HeaderBg),
 		EmptyStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
+			Foreground(c.Muted).
 			Italic(true),
 		HelpStyle: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
+			Foreground(c.Muted).
 			Padding(0, 1),
+		Content: render.NewStyles(),
 	}
 }
 
+// PanelOptions holds per-panel display state.
+type PanelOptions struct {
+	ScrollPos int  // -1 = follow bottom, >= 0 = fixed start line
+	Focused   bool // panel has keyboard focus
+	Pinned    bool // session is pinned to this panel
+}
+
+// panelDiffLines is the maximum number of diff lines shown for a file edit in a panel.
+const panelDiffLines = 12
+
+// panelCacheLimit is the number of recently rendered sessions whose rendered lines are kept.
+const panelCacheLimit = 32
+
 // Renderer handles panel rendering with styles.
 type Renderer struct {
-	styles *Styles
+	styles     *Styles
+	content    *render.Renderer
+	filter     *filter.Filter           // blocks to hide; nil shows everything
+	markdown   bool                     // render assistant text as Markdown
+	timestamps render.TimestampMode     // message times, idle gaps and durations
+	caches     map[string]*render.Cache // session ID -> rendered lines of its messages
+	recent     []string                 // IDs of the sessions in caches, most recently rendered last
 }
 
 // NewRenderer creates a new Renderer.
 func NewRenderer(styles *Styles) *Renderer {
-	return &Renderer{styles: styles}
+	return &Renderer{styles: styles, content: render.NewRenderer(styles.Content)}
+}
+
+// SetFilter sets the filter that selects which blocks are rendered.
+func (r *Renderer) SetFilter(f *filter.Filter) {
+	r.filter = f
+}
+
+// SetMarkdown sets whether assistant text is rendered as Markdown.
+func (r *Renderer) SetMarkdown(on bool) {
+	r.markdown = on
+}
+
+// SetTimestamps sets how message times are shown.
+func (r *Renderer) SetTimestamps(mode render.TimestampMode) {
+	r.timestamps = mode
+}
+
+// SetStyles replaces the styles, e.g. after a theme change.
+func (r *Renderer) SetStyles(styles *Styles) {
+	r.styles = styles
+	r.content = render.NewRenderer(styles.Content)
+	r.caches = nil
+	r.recent = nil
 }
 
 // RenderPanel renders a single panel.
-func (r *Renderer) RenderPanel(sess *session.Session, width, height, scrollPos int) string {
+func (r *Renderer) RenderPanel(sess *session.Session, width, height int, opts PanelOptions) string {
 	if sess == nil {
-		return r.renderEmptyPanel(width, height)
+		return r.renderEmptyPanel(width, height, opts.Focused)
 	}
+	scrollPos := opts.ScrollPos
 
 	// Calculate inner dimensions.
 	innerWidth := width - 2   // border
@@ -85,7 +112,7 @@ func (r *Renderer) RenderPanel(sess *session.Session, width, height, scrollPos i
 	}
 
 	// Render header (account for scrollbar width).
-	header := r.renderHeader(sess, innerWidth-1)
+	header := r.renderHeader(sess, innerWidth-1, opts.Pinned)
 
 	headerHeight := lipgloss.Height(header)
 
@@ -130,10 +157,21 @@ func (r *Renderer) RenderPanel(sess *session.Session, width, height, scrollPos i
 
 	content := strings.Join(allLines, "\n")
 
-	return r.styles.PanelBorder.Render(content)
+	return r.panelBorder(opts.Focused).Render(content)
 }
 
-func (r *Renderer) renderEmptyPanel(width, height int) string {
+// panelBorder returns the border style, highlighted when the panel has focus.
+func (r *Renderer) panelBorder(focused bool) lipgloss.Style {
+	if focused {
+		return r.styles.PanelBorder.
+			Border(theme.BorderFor(true)).
+			BorderForeground(theme.Colors().Accent)
+	}
+
+	return r.styles.PanelBorder
+}
+
+func (r *Renderer) renderEmptyPanel(width, height int, focused bool) string {
 	innerWidth := width - 2
 	innerHeight := height - 2
 
@@ -173,17 +211,26 @@ func (r *Renderer) renderEmptyPanel(width, height int) string {
 
 	content := strings.Join(lines, "\n")
 
-	return r.styles.PanelBorder.Render(content)
+	return r.panelBorder(focused).Render(content)
 }
 
-func (r *Renderer) renderHeader(sess *session.Session, width int) string {
+func (r *Renderer) renderHeader(sess *session.Session, width int, pinned bool) string {
 	prefix := ""
+	if pinned {
+		prefix = "[PIN] "
+	}
 	if sess.IsSubagent {
-		prefix = "[SUB] "
+		prefix += "[SUB] "
+	}
+
+	// The active filter is shown after the ID.
+	suffix := ""
+	if summary := r.filter.String(); summary != "" {
+		suffix = "  " + summary
 	}
 
 	// Calculate available width for ID (with 1 space padding on each side).
-	availableWidth := width - 2 - runewidth.StringWidth(prefix)
+	availableWidth := width - 2 - runewidth.StringWidth(prefix) - runewidth.StringWidth(suffix)
 	if availableWidth < 3 {
 		availableWidth = 3
 	}
@@ -195,8 +242,12 @@ func (r *Renderer) renderHeader(sess *session.Session, width int) string {
 	}
 
 	// Build content and pad to exact width.
-	content := " " + prefix + id
+	content := " " + prefix + id + suffix
 	contentWidth := runewidth.StringWidth(content)
+	if contentWidth > width {
+		content = runewidth.Truncate(content, width, "")
+		contentWidth = runewidth.StringWidth(content)
+	}
 	if contentWidth < width {
 		content += strings.Repeat(" ", width-contentWidth)
 	}
@@ -213,15 +264,7 @@ func (r *Renderer) renderBodyWithInfo(sess *session.Session, width, height, scro
 		return padded, 0
 	}
 
-	lines := make([]string, 0, len(sess.Messages)*3)
-
-	// Render messages from oldest to newest.
-	for i := range sess.Messages {
-		msg := sess.Messages[i]
-		msgLines := r.renderMessage(msg, width)
-		lines = append(lines, msgLines...)
-	}
-
+	lines := r.renderDocument(sess, width).Lines
 	totalLines := len(lines)
 
 	// Calculate visible window.
@@ -229,16 +272,102 @@ func (r *Renderer) renderBodyWithInfo(sess *session.Session, width, height, scro
 	// scrollPos >= 0 means fixed mode (scrollPos is the start line index).
 	startPos, endPos := calculateVisibleWindow(totalLines, height, scrollPos)
 
-	// Extract visible lines and pad each to fixed width using runewidth.
+	// Style only the visible lines and pad each to fixed width using runewidth.
 	visibleLines := lines[startPos:endPos]
 	paddedLines := make([]string, len(visibleLines))
 	for i, line := range visibleLines {
-		paddedLines[i] = padToWidth(line, width)
+		paddedLines[i] = padToWidth(line.String(), width)
 	}
 
 	return strings.Join(paddedLines, "\n"), totalLines
 }
 
+// renderDocument renders all messages of a session from oldest to newest.
+// Messages rendered before with the same inputs are taken from the session's cache.
+func (r *Renderer) renderDocument(sess *session.Session, width int) render.Document {
+	return r.content.Session(sess.Messages, render.Options{
+		Width:        width,
+		Filter:       r.filter,
+		MaxDiffLines: panelDiffLines,
+		Markdown:     r.markdown,
+		Timestamps:   r.timestamps,
+		Now:          time.Now(),
+		Cache:        r.cache(sess.ID),
+	})
+}
+
+// cache returns the render cache of a session.
+// Sessions come and go from the panels, so the caches of the sessions rendered least recently
+// are dropped beyond panelCacheLimit.
+func (r *Renderer) cache(sessionID string) *render.Cache {
+	r.recent = slices.DeleteFunc(r.recent, func(id string) bool { return id == sessionID })
+	r.recent = append(r.recent, sessionID)
+	if c, ok := r.caches[sessionID]; ok {
+		return c
+	}
+
+	if r.caches == nil {
+		r.caches = make(map[string]*render.Cache)
+	}
+	for len(r.recent) > panelCacheLimit {
+		delete(r.caches, r.recent[0])
+		r.recent = r.recent[1:]
+	}
+	c := render.NewCache()
+	r.caches[sessionID] = c
+
+	return c
+}
+
+// panelBodySize returns the body dimensions of a panel, matching RenderPanel:
+// border (2) and scrollbar (1) horizontally, border (2) and header (1) vertically.
+func panelBodySize(width, height int) (int, int) {
+	return width - 3, height - 3
+}
+
+// BodyMetrics returns the rendered line count and the visible body height of a panel.
+// width and height are the same as for RenderPanel.
+func (r *Renderer) BodyMetrics(sess *session.Session, width, height int) (int, int) {
+	bodyWidth, bodyHeight := panelBodySize(width, height)
+	if sess == nil || bodyWidth < 9 || bodyHeight < 1 {
+		return 0, max(0, bodyHeight)
+	}
+
+	doc := r.renderDocument(sess, bodyWidth)
+
+	return len(doc.Lines), bodyHeight
+}
+
+// ReadCount returns the number of messages visible up to the bottom of a panel,
+// i.e. the index of the last visible message + 1.
+// width, height and scrollPos are the same as for RenderPanel.
+func (r *Renderer) ReadCount(sess *session.Session, width, height, scrollPos int) int {
+	if sess == nil {
+		return 0
+	}
+
+	bodyWidth, bodyHeight := panelBodySize(width, height)
+	if bodyWidth < 9 || bodyHeight < 0 {
+		return 0
+	}
+
+	doc := r.renderDocument(sess, bodyWidth)
+	_, endPos := calculateVisibleWindow(len(doc.Lines), bodyHeight, scrollPos)
+	if endPos >= len(doc.Lines) {
+		return len(doc.MsgStarts)
+	}
+
+	count := 0
+	for i, start := range doc.MsgStarts {
+		if start >= endPos {
+			break
+		}
+		count = i + 1
+	}
+
+	return count
+}
+
 // calculateVisibleWindow calculates the start and end positions for visible content.
 // scrollPos = -1 means follow mode, >= 0 means fixed start line.
 func calculateVisibleWindow(totalLines, height, scrollPos int) (int, int) {
@@ -318,14 +447,16 @@ func truncateWithANSI(s string, width int) string {
 // renderScrollbar renders a scrollbar indicator.
 // scrollPos: -1 = follow mode (at bottom), >= 0 = fixed start line.
 func (r *Renderer) renderScrollbar(height, totalLines, visibleLines, scrollPos int) string {
-	scrollbarStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	thumbStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	c := theme.Colors()
+	g := theme.Symbols()
+	scrollbarStyle := lipgloss.NewStyle().Foreground(c.Border)
+	thumbStyle := lipgloss.NewStyle().Foreground(c.Accent)
 
 	// If all content fits, show empty track.
 	if totalLines <= visibleLines {
 		var lines []string
 		for range height {
-			lines = append(lines, scrollbarStyle.Render("│"))
+			lines = append(lines, scrollbarStyle.Render(g.ScrollTrack))
 		}
 
 		return strings.Join(lines, "\n")
@@ -365,313 +496,11 @@ func (r *Renderer) renderScrollbar(height, totalLines, visibleLines, scrollPos i
 	var lines []string
 	for i := range height {
 		if i >= thumbPos && i < thumbPos+thumbHeight {
-			lines = append(lines, thumbStyle.Render("┃"))
+			lines = append(lines, thumbStyle.Render(g.ScrollThumb))
 		} else {
-			lines = append(lines, scrollbarStyle.Render("│"))
+			lines = append(lines, scrollbarStyle.Render(g.ScrollTrack))
 		}
 	}
 
 	return strings.Join(lines, "\n")
 }
-
-func (r *Renderer) renderMessage(msg parser.Message, width int) []string {
-	var lines []string
-
-	for _, block := range msg.Message.Content {
-		blockLines := r.renderContentBlock(block, width, msg.Type)
-		lines = append(lines, blockLines...)
-	}
-
-	return lines
-}
-
-func (r *Renderer) renderContentBlock(block parser.ContentBlock, width int, msgType string) []string {
-	var lines []string
-
-	// Helper to ensure line fits within width (truncate before style application).
-	ensureWidth := func(text string, maxWidth int) string {
-		if runewidth.StringWidth(text) > maxWidth {
-			return runewidth.Truncate(text, maxWidth, "")
-		}
-
-		return text
-	}
-
-	// Handle user messages.
-	if msgType == "user" { //nolint:nestif // user message handling has necessary nested conditions
-		if block.Type == "text" && block.Text != "" {
-			label := r.styles.LabelStyle.Render("[USER] ")
-			labelWidth := lipgloss.Width(label)
-			indent := strings.Repeat(" ", labelWidth)
-			contentWidth := width - labelWidth
-			if contentWidth < 1 {
-				contentWidth = 1
-			}
-			wrapped := wrapText(block.Text, contentWidth)
-			for i, line := range wrapped {
-				line = ensureWidth(line, contentWidth)
-				if i == 0 {
-					lines = append(lines, label+r.styles.UserStyle.Render(line))
-				} else {
-					lines = append(lines, indent+r.styles.UserStyle.Render(line))
-				}
-			}
-		}
-
-		return lines
-	}
-
-	switch block.Type {
-	case "thinking":
-		text := block.Thinking
-		if text == "" {
-			text = block.Text
-		}
-		if text != "" {
-			label := r.styles.LabelStyle.Render("[THINK] ")
-			labelWidth := lipgloss.Width(label)
-			contentWidth := width - labelWidth
-			if contentWidth < 1 {
-				contentWidth = 1
-			}
-			content := r.styles.ThinkStyle.Render(truncateText(text, contentWidth))
-			lines = append(lines, label+content)
-		}
-
-	case "text":
-		if block.Text != "" {
-			label := r.styles.LabelStyle.Render("[TEXT] ")
-			labelWidth := lipgloss.Width(label)
-			indent := strings.Repeat(" ", labelWidth)
-			contentWidth := width - labelWidth
-			if contentWidth < 1 {
-				contentWidth = 1
-			}
-			wrapped := wrapText(block.Text, contentWidth)
-			for i, line := range wrapped {
-				line = ensureWidth(line, contentWidth)
-				if i == 0 {
-					lines = append(lines, label+r.styles.TextStyle.Render(line))
-				} else {
-					lines = append(lines, indent+r.styles.TextStyle.Render(line))
-				}
-			}
-		}
-
-	case "tool_use":
-		label := r.styles.LabelStyle.Render("[TOOL] ")
-		labelWidth := lipgloss.Width(label)
-		indent := strings.Repeat(" ", labelWidth)
-		contentWidth := width - labelWidth
-		if contentWidth < 1 {
-			contentWidth = 1
-		}
-		// Truncate tool name if needed.
-		toolNameTrunc := truncateText(block.Name, contentWidth)
-		toolName := r.styles.ToolStyle.Render(toolNameTrunc)
-		lines = append(lines, label+toolName)
-
-		// Show tool input.
-		if block.Input != nil {
-			inputStr := formatToolInput(block.Input, contentWidth)
-			for _, line := range inputStr {
-				line = ensureWidth(line, contentWidth)
-				lines = append(lines, indent+r.styles.ToolInputStyle.Render(line))
-			}
-		}
-
-	case "tool_result":
-		label := r.styles.LabelStyle.Render("[RESULT] ")
-		labelWidth := lipgloss.Width(label)
-		contentWidth := width - labelWidth
-		if contentWidth < 1 {
-			contentWidth = 1
-		}
-		if block.Text != "" {
-			content := truncateText(block.Text, contentWidth)
-			lines = append(lines, label+r.styles.TextStyle.Render(content))
-		}
-	}
-
-	return lines
-}
-
-func truncateText(text string, maxWidth int) string {
-	if maxWidth < 4 {
-		maxWidth = 4
-	}
-
-	// Remove newlines and tabs, then truncate.
-	text = strings.ReplaceAll(text, "\n", " ")
-	text = strings.ReplaceAll(text, "\r", "")
-	text = strings.ReplaceAll(text, "\t", " ")
-
-	if runewidth.StringWidth(text) <= maxWidth {
-		return text
-	}
-
-	// Truncate with "..." suffix, ensuring result fits in maxWidth.
-	truncated := runewidth.Truncate(text, maxWidth, "...")
-
-	// Double-check: if still over maxWidth, truncate more aggressively.
-	for runewidth.StringWidth(truncated) > maxWidth {
-		runes := []rune(strings.TrimSuffix(truncated, "..."))
-		if len(runes) <= 1 {
-			return "..."
-		}
-		truncated = string(runes[:len(runes)-1]) + "..."
-	}
-
-	return truncated
-}
-
-func wrapText(text string, width int) []string {
-	if width <= 0 {
-		return []string{text}
-	}
-
-	var lines []string
-	text = strings.ReplaceAll(text, "\r", "")
-	paragraphs := strings.Split(text, "\n")
-
-	for _, para := range paragraphs {
-		if para == "" {
-			lines = append(lines, "")
-
-			continue
-		}
-
-		for runewidth.StringWidth(para) > width {
-			// Find a good break point.
-			breakAt := findBreakPoint(para, width)
-			if breakAt <= 0 {
-				// Safety: avoid infinite loop.
-				breakAt = 1
-			}
-			line := para[:breakAt]
-			// Double-check: ensure line fits in width.
-			if runewidth.StringWidth(line) > width {
-				line = runewidth.Truncate(line, width, "")
-			}
-			lines = append(lines, line)
-			para = strings.TrimLeft(para[breakAt:], " ")
-		}
-		if para != "" {
-			// Final check on remaining text.
-			if runewidth.StringWidth(para) > width {
-				para = runewidth.Truncate(para, width, "")
-			}
-			lines = append(lines, para)
-		}
-	}
-
-	return lines
-}
-
-// findBreakPoint finds a good position to break text at the given width.
-func findBreakPoint(text string, width int) int {
-	// Build a slice of (bytePos, runeWidth) for each rune.
-	type runeInfo struct {
-		bytePos  int
-		endByte  int
-		cumWidth int
-		isSpace  bool
-	}
-
-	runes := make([]runeInfo, 0, len(text))
-	currentWidth := 0
-
-	for i, r := range text {
-		rw := runewidth.RuneWidth(r)
-		endByte := i + len(string(r))
-		runes = append(runes, runeInfo{
-			bytePos:  i,
-			endByte:  endByte,
-			cumWidth: currentWidth + rw,
-			isSpace:  r == ' ',
-		})
-		currentWidth += rw
-	}
-
-	if len(runes) == 0 {
-		return 0
-	}
-
-	// Find the last rune that fits within width.
-	breakRuneIdx := len(runes) - 1
-	for i, info := range runes {
-		if info.cumWidth > width {
-			if i > 0 {
-				breakRuneIdx = i - 1
-			} else {
-				breakRuneIdx = 0
-			}
-
-			break
-		}
-	}
-
-	// Try to find a space to break at (search backwards from breakRuneIdx).
-	for i := breakRuneIdx; i >= 0; i-- {
-		if runes[i].isSpace {
-			return runes[i].bytePos
-		}
-	}
-
-	// No space found, break at the calculated position.
-	if breakRuneIdx < len(runes) {
-		return runes[breakRuneIdx].endByte
-	}
-
-	return len(text)
-}
-
-// formatToolInput formats tool input for display.
-func formatToolInput(input any, maxWidth int) []string {
-	if input == nil {
-		return nil
-	}
-
-	// Try to format as JSON for readability.
-	inputMap, ok := input.(map[string]any)
-	if !ok {
-		str := fmt.Sprintf("%v", input)
-		// Replace newlines first, then truncate.
-		str = strings.ReplaceAll(str, "\n", "\\n")
-		str = strings.ReplaceAll(str, "\r", "")
-
-		return []string{truncateText(str, maxWidth)}
-	}
-
-	// Sort keys for stable display order.
-	keys := make([]string, 0, len(inputMap))
-	for key := range inputMap {
-		keys = append(keys, key)
-	}
-	sort.Strings(keys)
-
-	lines := make([]string, 0, len(keys))
-	for _, key := range keys {
-		value := inputMap[key]
-		var valueStr string
-		switch v := value.(type) {
-		case string:
-			// Replace newlines for display.
-			valueStr = strings.ReplaceAll(v, "\n", "\\n")
-			valueStr = strings.ReplaceAll(valueStr, "\r", "")
-		default:
-			jsonBytes, err := json.Marshal(v)
-			if err != nil {
-				valueStr = fmt.Sprintf("%v", v)
-			} else {
-				valueStr = string(jsonBytes)
-			}
-		}
-
-		// Format as "key: value" and truncate to maxWidth.
-		line := fmt.Sprintf("%s: %s", key, valueStr)
-		lines = append(lines, truncateText(line, maxWidth))
-	}
-
-	return lines
-}
@@ -3,9 +3,14 @@ package tui
 import (
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/sters/cc-session-tailing/internal/filter"
+	"github.com/sters/cc-session-tailing/internal/render"
 	"github.com/sters/cc-session-tailing/internal/session"
+	"github.com/sters/cc-session-tailing/internal/state"
+	"github.com/sters/cc-session-tailing/internal/theme"
 	"github.com/sters/cc-session-tailing/internal/tui/components"
 )
 
@@ -15,6 +20,30 @@ type HighlightClearMsg struct{}
 // highlightDuration is how long highlights stay visible.
 const highlightDuration = 500 * time.Millisecond
 
+// FollowMode is whether the tree selection follows the most active session.
+type FollowMode int
+
+const (
+	// FollowOff leaves the selection to the user.
+	FollowOff FollowMode = iota
+	// FollowOn selects the session that most recently received messages on each refresh.
+	FollowOn
+	// FollowPaused is follow mode interrupted by the user selecting a session or reading the log.
+	FollowPaused
+)
+
+// String returns the mode name.
+func (f FollowMode) String() string {
+	switch f {
+	case FollowOn:
+		return "on"
+	case FollowPaused:
+		return "paused"
+	default:
+		return "off"
+	}
+}
+
 // Focus represents which component has focus in tree view.
 type Focus int
 
@@ -35,6 +64,17 @@ type TreeView struct {
 	height     int
 	manager    *session.Manager
 	renderer   *Renderer
+	restore    *state.State // saved state applied on the next full refresh
+	treeWidth  int          // tree width set by dragging the divider; 0 = automatic
+	dragging   bool         // divider is being dragged
+	lastClick  treeClick    // previous click in the tree, for double-click detection
+	keys       *KeyMap
+	notice     string // shown instead of the help line until the next key
+	sortKey    session.SortKey
+	groupKey   session.GroupKey
+	follow     FollowMode
+	archived   *session.Session  // read-only session opened from a search; shown in the log instead of the selection
+	groups     map[string]string // group header of each root session shown in the tree
 }
 
 // NewTreeView creates a new tree view.
@@ -45,13 +85,22 @@ func NewTreeView(manager *session.Manager) *TreeView {
 	tree.SetFocused(true)
 	log.SetFocused(false)
 
-	return &TreeView{
+	tv := &TreeView{
 		tree:     tree,
 		log:      log,
 		focus:    FocusTree,
 		manager:  manager,
 		renderer: NewRenderer(NewStyles()),
 	}
+	tv.setKeyMap(DefaultKeyMap())
+
+	return tv
+}
+
+// setKeyMap sets the key bindings of the tree view and its log viewport.
+func (tv *TreeView) setKeyMap(k *KeyMap) {
+	tv.keys = k
+	tv.log.SetKeyMap(k.ViewportKeyMap())
 }
 
 // SetSize sets the dimensions of the tree view.
@@ -70,7 +119,23 @@ func (tv *TreeView) updateLayout() {
 		return
 	}
 
-	// Tree takes 30% of width (min 20, max 40).
+	treeWidth := tv.currentTreeWidth()
+	logWidth := tv.width - treeWidth
+
+	tv.tree.SetSize(treeWidth, tv.height)
+	tv.log.SetSize(logWidth, tv.height)
+}
+
+// currentTreeWidth returns the width of the tree pane.
+// Without a dragged width, the tree takes 30% of the width (min 20, max 40).
+func (tv *TreeView) currentTreeWidth() int {
+	if tv.treeHidden {
+		return 0
+	}
+	if tv.treeWidth > 0 {
+		return clampTreeWidth(tv.treeWidth, tv.width)
+	}
+
 	treeWidth := tv.width * 30 / 100
 	if treeWidth < 20 {
 		treeWidth = 20
@@ -79,10 +144,7 @@ func (tv *TreeView) updateLayout() {
 		treeWidth = 40
 	}
 
-	logWidth := tv.width - treeWidth
-
-	tv.tree.SetSize(treeWidth, tv.height)
-	tv.log.SetSize(logWidth, tv.height)
+	return treeWidth
 }
 
 // Update handles messages for tree view.
@@ -97,14 +159,74 @@ func (tv *TreeView) Update(msg tea.Msg) tea.Cmd {
 		return tv.log.Update(msg)
 	}
 
-	switch keyMsg.String() {
-	case "enter":
+	// While the search prompt is open, every key goes to the log viewport.
+	if tv.log.Searching() {
+		cmd := tv.log.Update(keyMsg)
+		tv.markRead()
+
+		return cmd
+	}
+
+	k := tv.keys
+	switch {
+	case key.Matches(keyMsg, k.LogSearch):
+		tv.setFocus(FocusLog)
+		tv.log.StartSearch()
+
+		return nil
+	case key.Matches(keyMsg, k.NextMatch, k.PrevMatch):
+		if tv.focus == FocusLog && tv.log.HasSearch() {
+			if key.Matches(keyMsg, k.NextMatch) {
+				tv.log.NextMatch()
+			} else {
+				tv.log.PrevMatch()
+			}
+			tv.markRead()
+
+			return nil
+		}
+	case key.Matches(keyMsg, k.Open):
 		if tv.focus == FocusTree {
 			tv.setFocus(FocusLog)
 
 			return nil
 		}
-	case "esc":
+		tv.log.ToggleBlock()
+		tv.markRead()
+
+		return nil
+	case key.Matches(keyMsg, k.NextBlock, k.PrevBlock):
+		if tv.focus == FocusLog {
+			if key.Matches(keyMsg, k.NextBlock) {
+				tv.log.NextBlock()
+			} else {
+				tv.log.PrevBlock()
+			}
+			tv.markRead()
+
+			return nil
+		}
+	case key.Matches(keyMsg, k.ExpandThinking):
+		tv.log.ToggleExpandThinking()
+		tv.markRead()
+
+		return nil
+	case key.Matches(keyMsg, k.ExpandTools):
+		tv.log.ToggleExpandToolIO()
+		tv.markRead()
+
+		return nil
+	case key.Matches(keyMsg, k.Back):
+		if tv.focus == FocusLog && tv.log.HasSearch() {
+			tv.log.ClearSearch()
+
+			return nil
+		}
+		if tv.focus == FocusLog && tv.log.HasCursor() {
+			tv.log.ClearCursor()
+
+			return nil
+		}
 		if tv.focus == FocusLog {
 			if tv.treeHidden {
 				tv.treeHidden = false
@@ -114,31 +236,60 @@ func (tv *TreeView) Update(msg tea.Msg) tea.Cmd {
 
 			return nil
 		}
-	case "f":
+	case key.Matches(keyMsg, k.Fullscreen):
 		if tv.focus == FocusLog {
 			tv.treeHidden = !tv.treeHidden
 			tv.updateLayout()
 
 			return nil
 		}
-	case "r":
-		// Sort tree by last update time.
+	case key.Matches(keyMsg, k.SortByTime):
+		// Sort the tree once by the chosen order, or by last update time in arrival order.
 		tv.RefreshSessionsSorted()
 
 		return nil
-	case "j", "down":
+	case key.Matches(keyMsg, k.SortBy):
+		tv.SetSort(tv.sortKey.Next())
+		tv.notice = "Sort: " + tv.sortKey.String()
+
+		return nil
+	case key.Matches(keyMsg, k.GroupBy):
+		tv.SetGroup(tv.groupKey.Next())
+		tv.notice = "Group: " + tv.groupKey.String()
+
+		return nil
+	case key.Matches(keyMsg, k.Follow):
+		tv.ToggleFollow()
+		tv.notice = "Follow: " + tv.follow.String()
+
+		return nil
+	case key.Matches(keyMsg, k.NextUnread):
+		// Jump to the next session with unread messages.
+		if tv.tree.SelectNextUnread() {
+			tv.showSelection()
+		}
+
+		return nil
+	case key.Matches(keyMsg, k.Collapse, k.Expand, k.ToggleNode, k.CollapseAll, k.ExpandAll):
+		if tv.focus == FocusTree {
+			tv.fold(keyMsg)
+
+			return nil
+		}
+	case key.Matches(keyMsg, k.Down):
 		if tv.focus == FocusTree {
 			tv.tree.MoveDown()
-			tv.updateLogSession()
+			tv.showSelection()
 		} else {
 			tv.log.ScrollDown()
+			tv.markRead()
 		}
 
 		return nil
-	case "k", "up":
+	case key.Matches(keyMsg, k.Up):
 		if tv.focus == FocusTree {
 			tv.tree.MoveUp()
-			tv.updateLogSession()
+			tv.showSelection()
 		} else {
 			tv.log.ScrollUp()
 		}
@@ -151,7 +302,74 @@ func (tv *TreeView) Update(msg tea.Msg) tea.Cmd {
 		return tv.tree.Update(keyMsg)
 	}
 
-	return tv.log.Update(keyMsg)
+	cmd := tv.log.Update(keyMsg)
+	tv.markRead()
+
+	return cmd
+}
+
+// fold expands or collapses sessions in the tree.
+// Collapse on a collapsed session or a leaf selects the parent; expand on an expanded session selects its first child.
+func (tv *TreeView) fold(msg tea.KeyMsg) {
+	prev := tv.tree.SelectedSession()
+
+	switch k := tv.keys; {
+	case key.Matches(msg, k.Collapse):
+		if !tv.tree.SetExpanded(false) {
+			tv.tree.MoveToParent()
+		}
+	case key.Matches(msg, k.Expand):
+		if !tv.tree.SetExpanded(true) {
+			tv.tree.MoveToChild()
+		}
+	case key.Matches(msg, k.ToggleNode):
+		tv.tree.ToggleExpanded()
+	case key.Matches(msg, k.CollapseAll):
+		tv.tree.SetAllExpanded(false)
+	case key.Matches(msg, k.ExpandAll):
+		tv.tree.SetAllExpanded(true)
+	}
+
+	// Collapsing an ancestor of the selected session selects the ancestor.
+	if tv.tree.SelectedSession() != prev {
+		tv.showSelection()
+	}
+}
+
+// CapturesInput returns whether a text prompt is consuming all key input.
+func (tv *TreeView) CapturesInput() bool {
+	return tv.log.Searching()
+}
+
+// Filter returns the block filter of the log viewport.
+func (tv *TreeView) Filter() *filter.Filter {
+	return tv.log.Filter()
+}
+
+// SetMarkdown sets whether the log viewport renders assistant text as Markdown.
+func (tv *TreeView) SetMarkdown(on bool) {
+	tv.log.SetMarkdown(on)
+}
+
+// SetTimestamps sets how the log viewport shows message times.
+func (tv *TreeView) SetTimestamps(mode render.TimestampMode) {
+	tv.log.SetTimestamps(mode)
+}
+
+// Restyle rebuilds the styles from the current theme.
+func (tv *TreeView) Restyle() {
+	tv.renderer.SetStyles(NewStyles())
+	tv.log.Restyle()
+}
+
+// SetNotice shows a message instead of the help line; an empty message shows the help again.
+func (tv *TreeView) SetNotice(text string) {
+	tv.notice = text
+}
+
+// SelectedSession returns the session selected in the tree.
+func (tv *TreeView) SelectedSession() *session.Session {
+	return tv.tree.SelectedSession()
 }
 
 // ClearHighlights clears all highlighted sessions.
@@ -172,33 +390,68 @@ func (tv *TreeView) View() string {
 
 	// Help line.
 	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("240")).
-		Padding(0, 1)
-
-	var help string
+		Foreground(theme.Colors().Muted).
+		Padding(0, 1).
+		MaxWidth(tv.width)
 
+	k := tv.keys
+	var helpText string
 	switch {
+	case tv.notice != "":
+		helpText = tv.notice
+	case tv.log.Searching():
+		helpText = "type to search | Enter: keep results | Esc: cancel"
 	case tv.focus == FocusTree:
-		help = helpStyle.Render("j/k: select | Enter: view logs | r: sort by time | t: panel mode | q: quit")
-	case tv.treeHidden:
-		help = helpStyle.Render("j/k: scroll | f: show tree | Esc: back to tree | t: panel mode | q: quit")
+		helpText = helpLine(
+			help("select", k.Down, k.Up),
+			help("view logs", k.Open),
+			help("expand/collapse", k.Expand, k.Collapse),
+			help("next unread", k.NextUnread),
+			help("follow: "+tv.follow.String(), k.Follow),
+			help("sort: "+tv.sortKey.String(), k.SortBy),
+			help("group: "+tv.groupKey.String(), k.GroupBy),
+			help("expand thinking/tool IO", k.ExpandThinking, k.ExpandTools),
+			help("markdown", k.Markdown),
+			help("times", k.Timestamps),
+			help("panel mode", k.ToggleView),
+			help("palette", k.Palette),
+			help("help", k.Help),
+			help("quit", k.Quit),
+		)
 	default:
-		help = helpStyle.Render("j/k: scroll | f: fullscreen | Esc: back to tree | t: panel mode | q: quit")
+		fullscreen := "fullscreen"
+		if tv.treeHidden {
+			fullscreen = "show tree"
+		}
+		helpText = helpLine(
+			help("scroll", k.Down, k.Up),
+			help("block", k.PrevBlock, k.NextBlock),
+			help("expand", k.Open, k.ExpandThinking, k.ExpandTools),
+			help("search", k.LogSearch),
+			help("match", k.NextMatch, k.PrevMatch),
+			help("filter", k.Filter, k.FilterKind),
+			help(fullscreen, k.Fullscreen),
+			help("back", k.Back),
+			help("help", k.Help),
+			help("quit", k.Quit),
+		)
 	}
 
-	return lipgloss.JoinVertical(lipgloss.Left, main, help)
+	return lipgloss.JoinVertical(lipgloss.Left, main, helpStyle.Render(helpText))
 }
 
-// RefreshSessions updates the session tree from the manager without sorting.
+// RefreshSessions updates the session tree from the manager in the selected sort order.
 // Returns a command to clear highlights after a delay if there are updates.
 func (tv *TreeView) RefreshSessions() tea.Cmd {
 	// Get recently updated sessions before refreshing.
 	updated := tv.manager.GetRecentlyUpdated()
 
-	// Use preserve-order version (no sorting).
-	nodes := tv.manager.GetSessionTreePreserveOrder()
-	tv.tree.SetSessionTree(nodes)
-	tv.updateLogSession()
+	tv.loadTree()
+	if tv.follow == FollowOn && len(updated) > 0 && tv.followMostActive(updated) {
+		tv.showLatest()
+	} else {
+		tv.updateLogSession()
+	}
 
 	// If there are updated sessions, highlight them.
 	if len(updated) > 0 {
@@ -213,17 +466,159 @@ func (tv *TreeView) RefreshSessions() tea.Cmd {
 	return nil
 }
 
-// RefreshSessionsSorted updates the session tree from the manager with sorting by last update time.
+// RefreshSessionsSorted updates the session tree from the manager with sorting
+// by the selected sort key, or by last update time in arrival order.
 func (tv *TreeView) RefreshSessionsSorted() {
-	nodes := tv.manager.GetSessionTree()
-	tv.tree.SetSessionTreeSorted(nodes)
+	tv.updateGroups(true)
+	tv.tree.SetSessionTreeSorted(tv.sortedNodes())
 	tv.updateLogSession()
 }
 
+// loadTree updates the tree from the manager, keeping the display order whatever the sort key:
+// sessions stay where they are and new sessions are added at the end of their group,
+// so that nothing moves under the cursor. The tree is sorted again only when a sort order
+// is chosen or the sessions are refreshed.
+func (tv *TreeView) loadTree() {
+	tv.updateGroups(false)
+	tv.tree.SetSessionTree(tv.manager.GetSessionTreePreserveOrder())
+}
+
+// updateGroups sets the group headers of the root sessions. Unless regroup is set,
+// sessions keep the group they are shown in and only new sessions get one.
+func (tv *TreeView) updateGroups(regroup bool) {
+	groups := tv.manager.GroupLabels(tv.groupKey, time.Now())
+	if !regroup && tv.groups != nil {
+		for id := range groups {
+			if group, ok := tv.groups[id]; ok {
+				groups[id] = group
+			}
+		}
+	}
+	tv.groups = groups
+	tv.tree.SetGroups(groups)
+}
+
+// sortedNodes returns the session tree sorted by the selected sort key, or by last update time in arrival order.
+func (tv *TreeView) sortedNodes() []*session.Node {
+	if tv.sortKey == session.SortArrival {
+		return tv.manager.GetSessionTree()
+	}
+
+	return tv.manager.GetSessionTreeSortedBy(tv.sortKey)
+}
+
+// SetSort sorts the tree by key from now on.
+func (tv *TreeView) SetSort(key session.SortKey) {
+	tv.sortKey = key
+	if tv.restore != nil {
+		return
+	}
+	tv.updateGroups(true)
+	tv.tree.SetSessionTreeSorted(tv.manager.GetSessionTreeSortedBy(key))
+	tv.updateLogSession()
+}
+
+// SetGroup groups the root sessions of the tree by key from now on.
+func (tv *TreeView) SetGroup(key session.GroupKey) {
+	tv.groupKey = key
+	if tv.restore != nil {
+		return
+	}
+	tv.updateGroups(true)
+	tv.tree.SetSessionTree(tv.manager.GetSessionTreePreserveOrder())
+	tv.updateLogSession()
+}
+
+// ToggleFollow turns follow mode off when it is on, and on otherwise.
+// Turning it on selects the most active session right away.
+func (tv *TreeView) ToggleFollow() {
+	if tv.follow == FollowOn {
+		tv.follow = FollowOff
+
+		return
+	}
+
+	tv.follow = FollowOn
+	if tv.restore != nil {
+		return
+	}
+	if tv.followMostActive(nil) {
+		tv.showLatest()
+
+		return
+	}
+	tv.updateLogSession()
+}
+
+// Follow returns the follow mode.
+func (tv *TreeView) Follow() FollowMode {
+	return tv.follow
+}
+
+// followMostActive selects the session with the latest message among ids (all sessions when nil)
+// and returns whether one was selected; showLatest then shows it.
+// When the user is reading the log, scrolled up or with a search or block cursor, follow mode is paused instead.
+func (tv *TreeView) followMostActive(ids map[string]bool) bool {
+	if tv.focus == FocusLog && (tv.log.YOffset() >= 0 || tv.log.HasSearch() || tv.log.HasCursor()) {
+		tv.pauseFollow()
+
+		return false
+	}
+
+	var latest *session.Session
+	for _, s := range tv.manager.GetAllSessions() {
+		if ids != nil && !ids[s.ID] {
+			continue
+		}
+		if latest == nil || s.LastActivity().After(latest.LastActivity()) {
+			latest = s
+		}
+	}
+	if latest == nil || !tv.tree.SelectSession(latest.ID) {
+		return false
+	}
+	tv.archived = nil
+
+	return true
+}
+
+// showLatest shows the selected session at the bottom of its log.
+// The log returns to where a session was last read; following shows its newest messages instead.
+func (tv *TreeView) showLatest() {
+	tv.log.SetSession(tv.tree.SelectedSession())
+	tv.log.GotoBottom()
+	tv.markRead()
+}
+
+// pauseFollow pauses follow mode after the user selected a session by hand.
+func (tv *TreeView) pauseFollow() {
+	if tv.follow == FollowOn {
+		tv.follow = FollowPaused
+		tv.notice = "Follow paused; " + tv.keys.Follow.Help().Key + " resumes"
+	}
+}
+
+// SortKey returns the sort order of the tree.
+func (tv *TreeView) SortKey() session.SortKey {
+	return tv.sortKey
+}
+
+// GroupKey returns the grouping of the tree.
+func (tv *TreeView) GroupKey() session.GroupKey {
+	return tv.groupKey
+}
+
 // RefreshSessionsSortedAndReset updates the session tree with sorting and resets selection to first item.
+// If saved state is pending, the saved order and selection are restored instead.
 func (tv *TreeView) RefreshSessionsSortedAndReset() {
-	nodes := tv.manager.GetSessionTree()
-	tv.tree.SetSessionTreeSorted(nodes)
+	if tv.restore != nil {
+		tv.applyRestore()
+
+		return
+	}
+
+	tv.updateGroups(true)
+	tv.tree.SetSessionTreeSorted(tv.sortedNodes())
 	tv.tree.ResetSelection()
 	tv.updateLogSession()
 }
@@ -231,6 +626,131 @@ func (tv *TreeView) RefreshSessionsSortedAndReset() {
 // RefreshLog refreshes the log viewport content.
 func (tv *TreeView) RefreshLog() {
 	tv.log.Refresh()
+	tv.markRead()
+}
+
+// OpenSession selects a session, focuses the log and scrolls it to the given message.
+// A non-empty query is highlighted in the log like a search.
+func (tv *TreeView) OpenSession(sessionID string, messageIndex int, query string) {
+	if tv.restore != nil {
+		tv.applyRestore()
+	}
+
+	// Pick up sessions loaded since the last refresh.
+	tv.loadTree()
+	if !tv.tree.SelectSession(sessionID) {
+		tv.updateLogSession()
+
+		return
+	}
+	tv.archived = nil
+	tv.pauseFollow()
+
+	tv.updateLogSession()
+	tv.log.ScrollToMessage(messageIndex)
+	if query != "" {
+		tv.log.SetSearchQuery(query)
+	} else {
+		tv.log.ClearSearch()
+	}
+	tv.setFocus(FocusLog)
+	tv.markRead()
+}
+
+// OpenArchived shows a session that the manager does not hold read-only in the log,
+// scrolled to the given message, until a session is selected in the tree.
+// A non-empty query is highlighted in the log like a search.
+func (tv *TreeView) OpenArchived(sess *session.Session, messageIndex int, query string) {
+	if tv.restore != nil {
+		tv.applyRestore()
+	}
+
+	tv.archived = sess
+	tv.pauseFollow()
+	tv.updateLogSession()
+	tv.log.ScrollToMessage(messageIndex)
+	if query != "" {
+		tv.log.SetSearchQuery(query)
+	} else {
+		tv.log.ClearSearch()
+	}
+	tv.setFocus(FocusLog)
+}
+
+// RestoreState schedules saved state to be applied on the next full refresh.
+func (tv *TreeView) RestoreState(st *state.State) {
+	tv.restore = st
+	tv.treeHidden = st.TreeHidden
+	tv.treeWidth = st.TreeWidth
+	tv.sortKey = session.ParseSortKey(st.TreeSort)
+	tv.groupKey = session.ParseGroupKey(st.TreeGroup)
+	if st.Follow {
+		tv.follow = FollowOn
+	}
+	tv.updateLayout()
+}
+
+func (tv *TreeView) applyRestore() {
+	st := tv.restore
+	tv.restore = nil
+
+	tv.tree.SetCollapsed(st.Collapsed)
+	tv.updateGroups(true)
+	if tv.sortKey == session.SortArrival {
+		tv.tree.SetSessionTreeOrdered(tv.manager.GetSessionTree(), st.TreeOrder)
+	} else {
+		tv.tree.SetSessionTreeSorted(tv.manager.GetSessionTreeSortedBy(tv.sortKey))
+	}
+	if st.SelectedSession == "" || !tv.tree.SelectSession(st.SelectedSession) {
+		tv.tree.ResetSelection()
+	}
+	tv.updateLogSession()
+	tv.log.SetYOffset(st.LogScroll)
+	tv.markRead()
+}
+
+// SaveState stores the tree view state into st.
+func (tv *TreeView) SaveState(st *state.State) {
+	st.TreeSort = ""
+	if tv.sortKey != session.SortArrival {
+		st.TreeSort = tv.sortKey.String()
+	}
+	st.TreeGroup = ""
+	if tv.groupKey != session.GroupNone {
+		st.TreeGroup = tv.groupKey.String()
+	}
+	st.Follow = tv.follow != FollowOff
+	if tv.restore != nil {
+		// Tree was never shown; keep the saved state as is.
+		st.TreeOrder = tv.restore.TreeOrder
+		st.SelectedSession = tv.restore.SelectedSession
+		st.Collapsed = tv.restore.Collapsed
+		st.TreeHidden = tv.restore.TreeHidden
+		st.TreeWidth = tv.restore.TreeWidth
+		st.LogScroll = tv.restore.LogScroll
+
+		return
+	}
+
+	st.TreeOrder = tv.tree.Order()
+	st.Collapsed = tv.tree.Collapsed()
+	st.TreeHidden = tv.treeHidden
+	st.TreeWidth = tv.treeWidth
+	if tv.archived == nil {
+		st.LogScroll = tv.log.YOffset()
+	}
+	if sess := tv.tree.SelectedSession(); sess != nil {
+		st.SelectedSession = sess.ID
+	}
+}
+
+// markRead advances the read cursor of the displayed session and refreshes unread counts.
+// A read-only session opened from a search has no read cursor.
+func (tv *TreeView) markRead() {
+	if sess := tv.tree.SelectedSession(); sess != nil && tv.archived == nil {
+		tv.manager.MarkRead(sess.ID, tv.log.ReadCount())
+	}
+	tv.tree.SetUnread(tv.manager.UnreadCounts())
 }
 
 func (tv *TreeView) setFocus(focus Focus) {
@@ -239,9 +759,22 @@ func (tv *TreeView) setFocus(focus Focus) {
 	tv.log.SetFocused(focus == FocusLog)
 }
 
+// showSelection shows the log of a session the user selected by hand,
+// pausing follow mode and closing a read-only session opened from a search.
+func (tv *TreeView) showSelection() {
+	tv.archived = nil
+	tv.pauseFollow()
+	tv.updateLogSession()
+}
+
+// updateLogSession shows the selected session, or the read-only session opened from a search, in the log.
 func (tv *TreeView) updateLogSession() {
 	sess := tv.tree.SelectedSession()
+	if tv.archived != nil {
+		sess = tv.archived
+	}
 	tv.log.SetSession(sess)
+	tv.markRead()
 }
 
 // GetFocus returns the current focus.
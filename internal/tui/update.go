@@ -1,20 +1,93 @@
 package tui
 
 import (
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sters/cc-session-tailing/internal/filter"
+	"github.com/sters/cc-session-tailing/internal/render"
 )
 
 // Update handles messages and updates the model.
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c":
+		m.setNotice("")
+
+		if m.palette.Active() && msg.String() != "ctrl+c" {
+			if item := m.palette.Update(msg); item != nil {
+				return m, m.runPaletteItem(item)
+			}
+
+			return m, nil
+		}
+
+		// The search overlay receives every key except ctrl+c.
+		if m.search.Active() && msg.String() != "ctrl+c" {
+			return m, m.search.Update(msg)
+		}
+
+		if m.filterMenu.Active() && msg.String() != "ctrl+c" {
+			if m.filterMenu.Update(msg) {
+				m.applyFilter()
+			}
+
+			return m, nil
+		}
+
+		if m.help.Active() && msg.String() != "ctrl+c" {
+			m.help.Update(msg, m.height)
+
+			return m, nil
+		}
+
+		// Text prompts receive every key except ctrl+c, including the global ones.
+		if m.viewMode == ViewModeTree && m.treeView.CapturesInput() && msg.String() != "ctrl+c" {
+			return m.updateTreeMode(msg)
+		}
+
+		if i := keyIndex(m.keys.FilterKind, msg.String()); i >= 0 && i < len(filter.Kinds) {
+			m.activeFilter().ToggleKind(filter.Kinds[i])
+			m.applyFilter()
+
+			return m, nil
+		}
+
+		switch {
+		case msg.String() == "ctrl+c" || key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
-		case "t":
+		case key.Matches(msg, m.keys.Palette):
+			m.palette.Open(m.paletteItems())
+
+			return m, nil
+		case key.Matches(msg, m.keys.Help):
+			m.help.Open()
+
+			return m, nil
+		case key.Matches(msg, m.keys.ToggleView):
 			cmd := m.ToggleViewMode()
 
 			return m, cmd
+		case key.Matches(msg, m.keys.Search):
+			m.search.Open()
+
+			return m, nil
+		case key.Matches(msg, m.keys.Filter):
+			m.openFilterMenu()
+
+			return m, nil
+		case key.Matches(msg, m.keys.FilterReset):
+			m.activeFilter().Reset()
+			m.applyFilter()
+
+			return m, nil
+		case key.Matches(msg, m.keys.Markdown):
+			m.setMarkdown(!m.markdown)
+
+			return m, nil
+		case key.Matches(msg, m.keys.Timestamps):
+			m.setTimestamps(m.timestamps.Next())
+
+			return m, nil
 		}
 
 		// Mode-specific key handling.
@@ -24,12 +97,17 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m.updatePanelMode(msg)
 
+	case tea.MouseMsg:
+		return m.updateMouse(msg)
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 		wasReady := m.ready
 		m.ready = true
 		m.treeView.SetSize(m.width, m.height)
+		m.search.SetSize(m.width, m.height)
+		m.palette.SetSize(m.width, m.height)
 
 		// Initialize tree view on first ready.
 		if !wasReady && m.viewMode == ViewModeTree {
@@ -37,19 +115,45 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			return m, nil
 		}
+		if m.viewMode == ViewModePanel {
+			// Fall back to fewer panels when the terminal shrinks below the minimum panel size.
+			if m.manager.PanelCount() > m.maxPanels() {
+				m.setPanelCount(m.maxPanels())
+			}
+			m.markPanelsRead()
+		}
 
 	case FileUpdateMsg:
 		m.processFileUpdate(msg.Event)
 		// Refresh tree view if in tree mode.
 		if m.viewMode == ViewModeTree {
 			highlightCmd := m.treeView.RefreshSessions()
-			m.treeView.RefreshLog()
 
 			return m, tea.Batch(waitForFileEvents(m.watcher), highlightCmd)
 		}
+		m.syncPanelFocus()
+		m.markPanelsRead()
 
 		return m, waitForFileEvents(m.watcher)
 
+	case GlobalSearchResultsMsg:
+		m.search.SetResults(msg)
+
+		return m, nil
+
+	case GlobalSearchOpenMsg:
+		m.openSearchResult(msg.Result)
+
+		return m, nil
+
+	case ClockTickMsg:
+		// Panels are rendered on every view; the log viewport keeps rendered lines.
+		if m.timestamps == render.TimestampsRelative && m.viewMode == ViewModeTree {
+			m.treeView.RefreshLog()
+		}
+
+		return m, clockTick()
+
 	case HighlightClearMsg:
 		// Clear highlights in tree view.
 		if m.viewMode == ViewModeTree {
@@ -69,98 +173,143 @@ func (m *Model) updateTreeMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *Model) updatePanelMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "j", "down":
-		m.scrollDown()
-	case "k", "up":
-		m.scrollUp()
-	case "p":
+	m.syncPanelFocus()
+
+	switch k := m.keys; {
+	case key.Matches(msg, k.Down):
+		m.scrollFocusedPanel(1)
+	case key.Matches(msg, k.Up):
+		m.scrollFocusedPanel(-1)
+	case key.Matches(msg, k.PageDown):
+		m.scrollFocusedPanel(m.pageSize())
+	case key.Matches(msg, k.PageUp):
+		m.scrollFocusedPanel(-m.pageSize())
+	case key.Matches(msg, k.HalfPageDown):
+		m.scrollFocusedPanel(max(1, m.pageSize()/2))
+	case key.Matches(msg, k.HalfPageUp):
+		m.scrollFocusedPanel(-max(1, m.pageSize()/2))
+	case key.Matches(msg, k.Top):
+		m.setPanelScroll(m.focusedPanelSession(), 0)
+	case key.Matches(msg, k.Bottom):
+		m.setPanelScroll(m.focusedPanelSession(), -1)
+	case key.Matches(msg, k.CyclePanels):
 		m.cyclePanelCount()
+	case key.Matches(msg, k.MorePanels):
+		m.setPanelCount(m.manager.PanelCount() + 1)
+	case key.Matches(msg, k.FewerPanels):
+		m.setPanelCount(m.manager.PanelCount() - 1)
+	case key.Matches(msg, k.Layout):
+		m.cycleLayout()
+	case key.Matches(msg, k.Zoom):
+		m.zoomed = !m.zoomed
+	case key.Matches(msg, k.NextPanel):
+		m.focusPanel(1)
+	case key.Matches(msg, k.PrevPanel):
+		m.focusPanel(-1)
+	case key.Matches(msg, k.Pin):
+		m.manager.TogglePin(m.focusedPanel)
+	case key.Matches(msg, k.SwapLeft):
+		m.swapFocusedPanel(-1)
+	case key.Matches(msg, k.SwapRight):
+		m.swapFocusedPanel(1)
 	}
+	m.markPanelsRead()
 
 	return m, nil
 }
 
+// cyclePanelCount increases the panel count, wrapping to 1 after the most that fit on screen.
 func (m *Model) cyclePanelCount() {
-	current := m.manager.PanelCount()
-	next := current + 1
-	if next > 5 {
+	next := m.manager.PanelCount() + 1
+	if next > m.maxPanels() {
 		next = 1
 	}
-	m.manager.SetPanelCount(next)
-	// Resize scrollPos array with -1 (follow bottom mode).
-	m.scrollPos = make([]int, next)
-	for i := range m.scrollPos {
-		m.scrollPos[i] = -1
+	m.setPanelCount(next)
+}
+
+// cycleLayout switches to the next layout preset.
+// Fixed layouts (e.g. 2x2) also set the panel count to fill the grid.
+func (m *Model) cycleLayout() {
+	m.layout = m.layout.next()
+	if m.layout.Kind == LayoutFixed {
+		m.setPanelCount(m.layout.Cols * m.layout.Rows)
 	}
+	m.syncPanelFocus()
 }
 
-func (m *Model) scrollDown() {
-	// Scroll down = show newer content.
-	// If in fixed mode (scrollPos >= 0), move start line forward.
-	// If we reach the bottom, switch to follow mode (scrollPos = -1).
-	sessions := m.manager.GetPanelSessions()
-	panels := m.manager.PanelCount()
-	panelHeight := m.height - 2 - 2 - 1 // total height - help line - border - header
+// setPanelCount sets the panel count, limited to what fits on screen.
+func (m *Model) setPanelCount(count int) {
+	count = max(1, min(count, m.maxPanels()))
+	m.manager.SetPanelCount(count)
+	m.syncPanelFocus()
+}
 
-	for i := range m.scrollPos {
-		if i >= panels {
-			continue
-		}
-		if m.scrollPos[i] < 0 {
-			// Already in follow mode.
-			continue
-		}
-		sess := sessions[i]
-		if sess == nil {
-			continue
-		}
-		// Estimate total lines.
-		totalLines := len(sess.Messages) * 3
-		maxStartLine := totalLines - panelHeight
-		if maxStartLine < 0 {
-			maxStartLine = 0
-		}
+// maxPanels returns the largest panel count that fits on screen with the current layout.
+func (m *Model) maxPanels() int {
+	return m.layout.maxPanels(m.width, m.height-2)
+}
 
-		m.scrollPos[i]++
-		// If we've scrolled past the bottom, switch to follow mode.
-		if m.scrollPos[i] >= maxStartLine {
-			m.scrollPos[i] = -1
-		}
+// swapFocusedPanel swaps the focused panel with its neighbor in the given direction.
+// Focus follows the moved panel.
+func (m *Model) swapFocusedPanel(delta int) {
+	target := m.focusedPanel + delta
+	if target < 0 || target >= m.manager.PanelCount() {
+		return
 	}
+
+	m.manager.SwapPanels(m.focusedPanel, target)
+	m.focusedPanel = target
+}
+
+// scrollFocusedPanel scrolls the focused panel by delta lines (positive = newer content).
+func (m *Model) scrollFocusedPanel(delta int) {
+	m.scrollPanel(m.focusedPanel, delta)
 }
 
-func (m *Model) scrollUp() {
-	// Scroll up = show older content.
-	// If in follow mode (scrollPos = -1), calculate current start line and fix it.
-	// Then move start line backward.
+// scrollPanel scrolls panel i by delta lines (positive = newer content).
+// Reaching the bottom switches the panel back to follow mode.
+func (m *Model) scrollPanel(i, delta int) {
 	sessions := m.manager.GetPanelSessions()
-	panels := m.manager.PanelCount()
-	panelHeight := m.height - 2 - 2 - 1 // total height - help line - border - header
+	if i < 0 || i >= len(sessions) || sessions[i] == nil {
+		return
+	}
+	sess := sessions[i]
 
-	for i := range m.scrollPos {
-		if i >= panels {
-			continue
-		}
-		sess := sessions[i]
-		if sess == nil {
-			continue
-		}
-		// Estimate total lines.
-		totalLines := len(sess.Messages) * 3
-		maxStartLine := totalLines - panelHeight
-		if maxStartLine < 0 {
-			maxStartLine = 0
-		}
+	width, height := m.panelSize(i)
+	totalLines, bodyHeight := m.renderer.BodyMetrics(sess, width, height)
+	maxStartLine := max(0, totalLines-bodyHeight)
 
-		if m.scrollPos[i] < 0 {
-			// Currently in follow mode, switch to fixed mode at current position.
-			m.scrollPos[i] = maxStartLine
-		}
+	pos := m.panelScroll(sess)
+	if pos < 0 {
+		// Currently in follow mode, switch to fixed mode at current position.
+		pos = maxStartLine
+	}
 
-		// Move start line backward (show older content).
-		if m.scrollPos[i] > 0 {
-			m.scrollPos[i]--
-		}
+	pos = max(0, min(pos+delta, maxStartLine))
+	if pos >= maxStartLine {
+		pos = -1
+	}
+	m.setPanelScroll(sess, pos)
+}
+
+// pageSize returns the number of lines scrolled by a page up/down.
+func (m *Model) pageSize() int {
+	_, height := panelBodySize(m.panelSize(m.focusedPanel))
+
+	return max(1, height-1)
+}
+
+// focusPanel moves panel focus by delta, wrapping around.
+func (m *Model) focusPanel(delta int) {
+	panels := m.manager.PanelCount()
+	m.setFocusedPanel(((m.focusedPanel+delta)%panels + panels) % panels)
+}
+
+// setFocusedPanel focuses panel i and the session it shows.
+func (m *Model) setFocusedPanel(i int) {
+	m.focusedPanel = i
+	m.focusedSession = ""
+	if sess := m.focusedPanelSession(); sess != nil {
+		m.focusedSession = sess.ID
 	}
 }
@@ -13,6 +13,19 @@ func (m *Model) View() string {
 		return "Initializing..."
 	}
 
+	if m.palette.Active() {
+		return m.palette.View()
+	}
+	if m.search.Active() {
+		return m.search.View()
+	}
+	if m.filterMenu.Active() {
+		return m.filterMenu.View(m.width, m.height)
+	}
+	if m.help.Active() {
+		return m.help.View(m.width, m.height)
+	}
+
 	if m.viewMode == ViewModeTree {
 		return m.renderTreeView()
 	}
@@ -25,35 +38,73 @@ func (m *Model) renderTreeView() string {
 }
 
 func (m *Model) renderPanelView() string {
-	// Calculate panel dimensions.
 	panels := m.manager.PanelCount()
-	panelWidth := m.width / panels
-	panelHeight := m.height - 2 // Leave room for help line.
+	rects := m.panelRects()
 
 	// Get sessions for each panel.
 	sessions := m.manager.GetPanelSessions()
 
-	// Render each panel.
-	panelViews := make([]string, 0, panels)
+	// Render each panel and group them into rows by their y position.
+	var rows []string
+	var row []string
+	rowY := -1
 	for i := range panels {
-		scrollPos := 0
-		if i < len(m.scrollPos) {
-			scrollPos = m.scrollPos[i]
+		rect := rects[i]
+		if rect.width == 0 {
+			continue // hidden while another panel is zoomed
+		}
+		if rect.y != rowY && len(row) > 0 {
+			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+			row = nil
 		}
+		rowY = rect.y
 
 		sess := sessions[i]
-		panel := m.renderer.RenderPanel(sess, panelWidth, panelHeight, scrollPos)
-		panelViews = append(panelViews, panel)
+		opts := PanelOptions{
+			ScrollPos: m.panelScroll(sess),
+			Focused:   i == m.focusedPanel,
+			Pinned:    m.manager.IsPinnedSlot(i),
+		}
+		panel := m.renderer.RenderPanel(sess, rect.width, rect.height, opts)
+		if panel == "" {
+			// Too small to render; keep the grid aligned.
+			panel = lipgloss.NewStyle().Width(rect.width).Height(rect.height).Render("")
+		}
+		row = append(row, panel)
+	}
+	if len(row) > 0 {
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
 	}
 
-	// Join panels horizontally.
-	panelsRow := lipgloss.JoinHorizontal(lipgloss.Top, panelViews...)
+	panelsView := lipgloss.JoinVertical(lipgloss.Left, rows...)
 
 	// Help line.
-	help := m.renderer.styles.HelpStyle.Render("q: quit | j/k: scroll | p: panels (%d) | t: tree mode | Watching for sessions...")
-	help = fmt.Sprintf(help, panels)
+	zoom := "zoom"
+	if m.zoomed {
+		zoom = "zoom [ZOOM]"
+	}
+	k := m.keys
+	helpText := helpLine(
+		help("quit", k.Quit),
+		help("help", k.Help),
+		help("palette", k.Palette),
+		help("focus", k.PrevPanel, k.NextPanel),
+		help("scroll", k.Down, k.Up),
+		help("pin", k.Pin),
+		help("swap", k.SwapLeft, k.SwapRight),
+		help(zoom, k.Zoom),
+		help(fmt.Sprintf("panels (%d)", panels), k.CyclePanels, k.MorePanels, k.FewerPanels),
+		help(fmt.Sprintf("layout (%s)", m.layout), k.Layout),
+		help("filter", k.Filter),
+		help("markdown", k.Markdown),
+		help("times", k.Timestamps),
+		help("tree", k.ToggleView),
+	)
+	if m.notice != "" {
+		helpText = m.notice
+	}
 
-	return lipgloss.JoinVertical(lipgloss.Left, panelsRow, help)
+	return lipgloss.JoinVertical(lipgloss.Left, panelsView, m.renderer.styles.HelpStyle.MaxWidth(m.width).Render(helpText))
 }
 
 // RenderWelcome renders a welcome message when no sessions are active.
@@ -68,6 +68,11 @@ func (w *Watcher) Stop() error {
 	return nil
 }
 
+// ProjectPath returns the watched project directory.
+func (w *Watcher) ProjectPath() string {
+	return w.projectPath
+}
+
 func (w *Watcher) addDirRecursive(dir string) error {
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, walkErr error) error {
 		if walkErr != nil || info == nil {